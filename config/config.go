@@ -1,25 +1,355 @@
 package config
 
 import (
+	"database_workload/sqltext"
 	"encoding/json"
+	"fmt"
+	"math"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config is the main configuration structure
 type Config struct {
-	Concurrency    int        `json:"concurrency"`
-	RatePerThread  int        `json:"rate_per_thread"`
+	Concurrency   int     `json:"concurrency"`
+	RatePerThread int     `json:"rate_per_thread"`
+	TargetTPS     float64 `json:"target_tps,omitempty"`
+	LoadModel     string  `json:"load_model,omitempty"` // "closed" (default) or "open"
+	Arrival       string  `json:"arrival,omitempty"`    // "constant" (default) or "poisson"
+
+	// RateSchedule varies the target aggregate rate over time. It replaces
+	// target_tps.
+	RateSchedule *RateSchedule `json:"rate_schedule,omitempty"`
+
 	DBConnStr      string     `json:"db_conn_str"`
 	ConnectionType string     `json:"connection_type,omitempty"`
 	UseTransaction bool       `json:"use_transaction"`
 	Templates      []Template `json:"templates"`
+
+	// SessionInit statements run on every new connection before it is used,
+	// e.g. SET time_zone or USE db.
+	SessionInit []string `json:"session_init,omitempty"`
+
+	// Transaction options of the top-level templates; see Transaction.
+	IsolationLevel   string `json:"isolation_level,omitempty"`
+	ReadOnly         bool   `json:"read_only,omitempty"`
+	StartTransaction string `json:"start_transaction,omitempty"`
+	ThinkTime        *Param `json:"think_time,omitempty"`
+
+	// SessionVars are generated once at the start of every session, so that
+	// all templates of the session can operate on the same values.
+	SessionVars map[string]Param `json:"session_vars,omitempty"`
+
+	// Transactions replaces Templates with a weighted mix of named
+	// transaction types; each session runs one of them.
+	Transactions []Transaction `json:"transactions,omitempty"`
+
+	// Retry re-runs transactions aborted by a retryable error.
+	Retry *RetryPolicy `json:"retry,omitempty"`
+
+	// Run limits. Zero means unlimited: the run stops on SIGINT/SIGTERM.
+	Duration             Duration `json:"duration,omitempty"`
+	MaxSessions          int64    `json:"max_sessions,omitempty"`
+	MaxSessionsPerWorker int64    `json:"max_sessions_per_worker,omitempty"`
+
+	// Phases replaces Duration with a warmup, measure and cooldown sequence.
+	Phases *Phases `json:"phases,omitempty"`
+
+	// FindMax configures the find-max run mode.
+	FindMax *FindMax `json:"find_max,omitempty"`
+}
+
+// FindMax configures a search for the highest sustainable load: the load is
+// increased in steps, each held for Hold, until a step violates the SLO.
+type FindMax struct {
+	Target string   `json:"target"` // "concurrency" or "rate"
+	Start  float64  `json:"start"`
+	Step   float64  `json:"step"`
+	Max    float64  `json:"max,omitempty"` // zero means no upper bound
+	Hold   Duration `json:"hold"`
+
+	// SLO. At least one must be set.
+	MaxP99       Duration `json:"max_p99,omitempty"`
+	MaxErrorRate float64  `json:"max_error_rate,omitempty"`
+}
+
+// Validate checks that the search is well defined.
+func (f *FindMax) Validate() error {
+	if f.Target != "concurrency" && f.Target != "rate" {
+		return fmt.Errorf("find_max target must be \"concurrency\" or \"rate\", got %q", f.Target)
+	}
+	if f.Start <= 0 || f.Step <= 0 || f.Hold <= 0 {
+		return fmt.Errorf("find_max requires positive start, step and hold")
+	}
+	if f.Target == "concurrency" && (f.Start != math.Trunc(f.Start) || f.Step != math.Trunc(f.Step)) {
+		return fmt.Errorf("find_max start and step must be whole numbers of workers")
+	}
+	if f.Max != 0 && f.Max < f.Start {
+		return fmt.Errorf("find_max max cannot be less than start")
+	}
+	if f.MaxP99 <= 0 && f.MaxErrorRate <= 0 {
+		return fmt.Errorf("find_max requires max_p99 or max_error_rate")
+	}
+	return nil
+}
+
+// RetryPolicy configures how sessions that use a transaction are retried
+// after a retryable error. Each retry re-runs the whole transaction with
+// the same session variables, after a random backoff of up to Backoff
+// doubled for every previous retry, capped at MaxBackoff.
+type RetryPolicy struct {
+	Codes       []uint16 `json:"codes,omitempty"`       // MySQL error numbers; default 9007, 1213, 1205
+	MaxAttempts int      `json:"max_attempts"`          // attempts including the first one
+	Backoff     Duration `json:"backoff,omitempty"`     // default 10ms
+	MaxBackoff  Duration `json:"max_backoff,omitempty"` // default 1s
+}
+
+// Validate checks that the retry policy allows at least one retry.
+func (r *RetryPolicy) Validate() error {
+	if r.MaxAttempts < 2 {
+		return fmt.Errorf("retry requires max_attempts of at least 2")
+	}
+	if r.MaxBackoff > 0 && r.MaxBackoff < r.Backoff {
+		return fmt.Errorf("retry max_backoff cannot be less than backoff")
+	}
+	return nil
+}
+
+// RateSchedule describes a target rate, in sessions per second, that varies
+// with the time since the start of the run.
+type RateSchedule struct {
+	Type string `json:"type"` // "ramp", "step", "sine" or "points"
+
+	// Ramp: linear from From to To over Duration, then To.
+	// Step: From, increased by Step every Every, up to To if set.
+	From     float64  `json:"from,omitempty"`
+	To       float64  `json:"to,omitempty"`
+	Duration Duration `json:"duration,omitempty"`
+	Step     float64  `json:"step,omitempty"`
+	Every    Duration `json:"every,omitempty"`
+
+	// Sine: Base + Amplitude * sin(2π t / Period), e.g. a diurnal curve.
+	Base      float64  `json:"base,omitempty"`
+	Amplitude float64  `json:"amplitude,omitempty"`
+	Period    Duration `json:"period,omitempty"`
+
+	// Points: linear interpolation between (at, rate) points, sorted by
+	// time. The last rate holds after the last point.
+	Points []RatePoint `json:"points,omitempty"`
+}
+
+// RatePoint is a target rate at a time since the start of the run.
+type RatePoint struct {
+	At   Duration `json:"at"`
+	Rate float64  `json:"rate"`
+}
+
+// Validate checks that the fields required by the schedule type are set.
+func (s *RateSchedule) Validate() error {
+	switch s.Type {
+	case "ramp":
+		if s.Duration <= 0 {
+			return fmt.Errorf("ramp rate_schedule requires a positive duration")
+		}
+	case "step":
+		if s.Every <= 0 || s.Step == 0 {
+			return fmt.Errorf("step rate_schedule requires step and a positive every")
+		}
+	case "sine":
+		if s.Period <= 0 || s.Base <= 0 {
+			return fmt.Errorf("sine rate_schedule requires a positive base and period")
+		}
+	case "points":
+		if len(s.Points) == 0 {
+			return fmt.Errorf("points rate_schedule requires at least one point")
+		}
+		for i := 1; i < len(s.Points); i++ {
+			if s.Points[i].At <= s.Points[i-1].At {
+				return fmt.Errorf("rate_schedule points must be sorted by strictly increasing time")
+			}
+		}
+	default:
+		return fmt.Errorf("unknown rate_schedule type: %s", s.Type)
+	}
+	return nil
+}
+
+// Phases splits a run into warmup, measured steady state and cooldown.
+// Load runs throughout, but only the measured phase is included in the
+// end-of-run summary.
+type Phases struct {
+	Warmup   Duration `json:"warmup,omitempty"`
+	Measure  Duration `json:"measure"`
+	Cooldown Duration `json:"cooldown,omitempty"`
+}
+
+// Total returns the length of the whole run.
+func (p *Phases) Total() time.Duration {
+	return time.Duration(p.Warmup + p.Measure + p.Cooldown)
+}
+
+// Duration is a time.Duration written in JSON as a string such as "90s" or "5m".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	if v < 0 {
+		return fmt.Errorf("duration cannot be negative: %s", s)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Transaction is a named list of templates run in order in one session.
+type Transaction struct {
+	Name           string           `json:"name"`
+	Weight         float64          `json:"weight"`
+	UseTransaction bool             `json:"use_transaction"`
+	SessionVars    map[string]Param `json:"session_vars,omitempty"`
+	Templates      []Template       `json:"templates"`
+
+	// IsolationLevel is "read_uncommitted", "read_committed",
+	// "repeatable_read" or "serializable"; the server default if empty.
+	IsolationLevel string `json:"isolation_level,omitempty"`
+	ReadOnly       bool   `json:"read_only,omitempty"`
+	// StartTransaction replaces the driver's BEGIN with a raw statement,
+	// e.g. "START TRANSACTION WITH CONSISTENT SNAPSHOT" or TiDB's
+	// "BEGIN PESSIMISTIC". The transaction then ends with a raw COMMIT or
+	// ROLLBACK.
+	StartTransaction string `json:"start_transaction,omitempty"`
+
+	// ThinkTime is the pause after every session, in milliseconds, drawn
+	// from a number distribution. It is not part of the session latency.
+	ThinkTime *Param `json:"think_time,omitempty"`
+}
+
+// validateTxOptions checks the transaction options of tx.
+func validateTxOptions(tx Transaction) error {
+	if !tx.UseTransaction && (tx.IsolationLevel != "" || tx.ReadOnly || tx.StartTransaction != "") {
+		return fmt.Errorf("isolation_level, read_only and start_transaction require use_transaction")
+	}
+	switch tx.IsolationLevel {
+	case "", "read_uncommitted", "read_committed", "repeatable_read", "serializable":
+	default:
+		return fmt.Errorf("unknown isolation_level: %s", tx.IsolationLevel)
+	}
+	if tx.StartTransaction == "" {
+		return nil
+	}
+	start := strings.ToUpper(strings.TrimSpace(tx.StartTransaction))
+	if !strings.HasPrefix(start, "START TRANSACTION") && !strings.HasPrefix(start, "BEGIN") {
+		return fmt.Errorf("start_transaction must be a START TRANSACTION or BEGIN statement")
+	}
+	if tx.ReadOnly {
+		return fmt.Errorf("read_only cannot be used with start_transaction; add READ ONLY to the statement")
+	}
+	return nil
+}
+
+// GetTransactions returns the transaction types of the workload. Without
+// transactions, the top-level templates form a single unnamed transaction
+// type.
+func (c *Config) GetTransactions() []Transaction {
+	if len(c.Transactions) > 0 {
+		return c.Transactions
+	}
+	return []Transaction{{
+		Weight:           1,
+		UseTransaction:   c.UseTransaction,
+		SessionVars:      c.SessionVars,
+		Templates:        c.Templates,
+		IsolationLevel:   c.IsolationLevel,
+		ReadOnly:         c.ReadOnly,
+		StartTransaction: c.StartTransaction,
+		ThinkTime:        c.ThinkTime,
+	}}
 }
 
 // Template represents a single SQL query template
 type Template struct {
-	SQL    string  `json:"sql"`
-	Params []Param `json:"params"`
-	Repeat int     `json:"repeat,omitempty"`
+	SQL     string    `json:"sql"`
+	Params  []Param   `json:"params"`
+	Repeat  int       `json:"repeat,omitempty"`
+	Capture []Capture `json:"capture,omitempty"`
+	// Mode forces the statement to be run as a "query", whose result set is
+	// read, or an "exec". By default it is inferred from the SQL.
+	Mode string `json:"mode,omitempty"`
+	// ThinkTime is the pause after every execution of the template, in
+	// milliseconds, drawn from a number distribution. The connection and
+	// transaction stay open meanwhile.
+	ThinkTime *Param `json:"think_time,omitempty"`
+	// ToleratedErrors lists MySQL error numbers, such as "1062", or classes
+	// from ErrorClasses, that are counted but neither abort the session nor
+	// roll back its transaction.
+	ToleratedErrors []string `json:"tolerated_errors,omitempty"`
+}
+
+// ErrorClasses groups MySQL error numbers that workloads commonly tolerate.
+var ErrorClasses = map[string][]uint16{
+	"duplicate_key": {1022, 1062, 1586},
+	"foreign_key":   {1216, 1217, 1451, 1452},
+	"conflict":      {1205, 1213, 9007}, // lock wait timeout, deadlock, write conflict
+}
+
+// ToleratedCodes returns the MySQL error numbers tolerated by the template.
+func (t *Template) ToleratedCodes() ([]uint16, error) {
+	var codes []uint16
+	for _, e := range t.ToleratedErrors {
+		if class, ok := ErrorClasses[e]; ok {
+			codes = append(codes, class...)
+			continue
+		}
+		code, err := strconv.ParseUint(e, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("tolerated error %q is neither an error number nor a known class", e)
+		}
+		codes = append(codes, uint16(code))
+	}
+	return codes, nil
+}
+
+// Capture binds a column of a query's result to a session variable that
+// later templates of the same session can use through a "var" param.
+type Capture struct {
+	Column string `json:"column"`
+	Var    string `json:"var"`
+	// Mode selects the value: "first" row (default), a "random" row, or
+	// "all" rows as an array. An empty result sets NULL, or an empty array
+	// for "all".
+	Mode string `json:"mode,omitempty"`
+}
+
+// ParamNames returns the name of each param, "" for unnamed ones.
+func (t *Template) ParamNames() []string {
+	names := make([]string, len(t.Params))
+	for i, p := range t.Params {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// IsQuery reports whether the template returns a result set.
+func (t *Template) IsQuery() bool {
+	switch t.Mode {
+	case "query":
+		return true
+	case "exec":
+		return false
+	}
+	return sqltext.IsQuery(t.SQL)
 }
 
 func (t *Template) GetRepeat() int {
@@ -31,6 +361,8 @@ func (t *Template) GetRepeat() int {
 
 // Param represents a parameter for a SQL query
 type Param struct {
+	// Name binds the param to the :name or {{name}} placeholders of the SQL.
+	Name       string `json:"name,omitempty"`
 	Type       string `json:"type"`
 	RandomMode string `json:"random_mode"`
 
@@ -39,6 +371,32 @@ type Param struct {
 	Max       *int64   `json:"max,omitempty"`
 	Exponent  *float64 `json:"exponent,omitempty"`
 	Partition *int64   `json:"partition,omitempty"`
+	Value     *int64   `json:"value,omitempty"`  // fixed
+	Mean      *float64 `json:"mean,omitempty"`   // exponential, normal, lognormal
+	Stddev    *float64 `json:"stddev,omitempty"` // normal, lognormal
+	Theta     *float64 `json:"theta,omitempty"`  // zipfian, scrambled_zipfian, latest
+
+	// Hotspot: HotAccessFraction of the values fall in a hot set covering
+	// HotDataFraction of [min, max], placed at HotOffset (0 = at min,
+	// 1 = at max).
+	HotDataFraction   *float64 `json:"hot_data_fraction,omitempty"`
+	HotAccessFraction *float64 `json:"hot_access_fraction,omitempty"`
+	HotOffset         *float64 `json:"hot_offset,omitempty"`
+
+	// Sequence names a counter shared by all workers: the "sequence" mode
+	// draws increasing values from it, and the "latest" mode favours the
+	// values it generated last.
+	Sequence *string `json:"sequence,omitempty"`
+
+	// Drift moves the values over time, e.g. to shift a hotspot.
+	Drift *Drift `json:"drift,omitempty"`
+
+	// Decimal and float: the number modes above draw the value in units of
+	// its last digit, 10^-Scale, e.g. min 1 and max 999999 for prices from
+	// 0.01 to 9999.99 with scale 2. Precision, when set, caps the number of
+	// digits as in DECIMAL(precision, scale).
+	Precision *int `json:"precision,omitempty"`
+	Scale     *int `json:"scale,omitempty"`
 
 	// String
 	Format       *string `json:"format,omitempty"`
@@ -56,6 +414,79 @@ type Param struct {
 	ArraySize     *int    `json:"array_size,omitempty"`
 	ElementType   *string `json:"element_type,omitempty"`
 	ElementConfig *Param  `json:"element_config,omitempty"`
+
+	// Var
+	Var *string `json:"var,omitempty"`
+}
+
+// Drift moves the values of a number distribution across [min, max] over
+// time, wrapping around at max, so that its hot region does not stay in
+// place. Set either Period, for a continuous move, or Every, for jumps.
+type Drift struct {
+	Period Duration `json:"period,omitempty"` // time to move across the whole range
+	Every  Duration `json:"every,omitempty"`  // interval between jumps
+	Jump   float64  `json:"jump,omitempty"`   // fraction of the range per jump; 0 jumps to random positions
+}
+
+// validateVars checks that every "var" param of tx refers to a session
+// variable or to a variable captured by an earlier template, and that only
+// queries capture.
+func validateVars(tx Transaction) error {
+	defined := make(map[string]bool)
+	for name, p := range tx.SessionVars {
+		if p.Type == "var" {
+			return fmt.Errorf("session variable %s cannot be of type var", name)
+		}
+		defined[name] = true
+	}
+	for i, tmpl := range tx.Templates {
+		for _, p := range tmpl.Params {
+			if p.Type != "var" {
+				continue
+			}
+			if p.Var == nil {
+				return fmt.Errorf("template %d: var param requires var", i)
+			}
+			if !defined[*p.Var] {
+				return fmt.Errorf("template %d: variable %s is neither a session variable nor captured by an earlier template", i, *p.Var)
+			}
+		}
+		if len(tmpl.Capture) > 0 && !tmpl.IsQuery() {
+			// Statements run as exec return no rows to capture from.
+			return fmt.Errorf("template %d: capture requires a query; set \"mode\": \"query\" if the statement returns rows", i)
+		}
+		for _, c := range tmpl.Capture {
+			if c.Column == "" || c.Var == "" {
+				return fmt.Errorf("template %d: capture requires column and var", i)
+			}
+			switch c.Mode {
+			case "", "first", "random", "all":
+			default:
+				return fmt.Errorf("template %d: unknown capture mode: %s", i, c.Mode)
+			}
+			defined[c.Var] = true
+		}
+	}
+	return nil
+}
+
+// validateTemplates checks that the placeholders of every template of tx
+// match its params, and that its mode and tolerated errors are known.
+func validateTemplates(tx Transaction) error {
+	for i, tmpl := range tx.Templates {
+		if _, err := sqltext.Parse(tmpl.SQL, tmpl.ParamNames()); err != nil {
+			return fmt.Errorf("template %d: %w", i, err)
+		}
+		switch tmpl.Mode {
+		case "", "query", "exec":
+		default:
+			return fmt.Errorf("template %d: mode must be \"query\" or \"exec\", got %q", i, tmpl.Mode)
+		}
+		if _, err := tmpl.ToleratedCodes(); err != nil {
+			return fmt.Errorf("template %d: %w", i, err)
+		}
+	}
+	return nil
 }
 
 // LoadConfig reads a configuration file and returns a Config struct
@@ -70,6 +501,95 @@ func LoadConfig(path string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
 
 	return &config, nil
 }
+
+// Validate checks settings that cannot be checked while decoding.
+func (c *Config) Validate() error {
+	if c.MaxSessions < 0 || c.MaxSessionsPerWorker < 0 {
+		return fmt.Errorf("max_sessions and max_sessions_per_worker cannot be negative")
+	}
+	if len(c.Transactions) > 0 && (len(c.Templates) > 0 || c.UseTransaction || len(c.SessionVars) > 0 ||
+		c.IsolationLevel != "" || c.ReadOnly || c.StartTransaction != "" || c.ThinkTime != nil) {
+		return fmt.Errorf("templates, use_transaction, session_vars, think_time and transaction options cannot be used with transactions; define them per transaction")
+	}
+	names := make(map[string]bool)
+	for _, tx := range c.Transactions {
+		if tx.Name == "" {
+			return fmt.Errorf("every transaction requires a name")
+		}
+		if names[tx.Name] {
+			return fmt.Errorf("duplicate transaction name: %s", tx.Name)
+		}
+		names[tx.Name] = true
+		if tx.Weight <= 0 {
+			return fmt.Errorf("transaction %s requires a positive weight", tx.Name)
+		}
+	}
+	for _, tx := range c.GetTransactions() {
+		err := validateVars(tx)
+		if err == nil {
+			err = validateTemplates(tx)
+		}
+		if err == nil {
+			err = validateTxOptions(tx)
+		}
+		if err != nil {
+			if tx.Name != "" {
+				return fmt.Errorf("transaction %s: %w", tx.Name, err)
+			}
+			return err
+		}
+	}
+	if c.TargetTPS < 0 {
+		return fmt.Errorf("target_tps cannot be negative")
+	}
+	if c.TargetTPS > 0 && c.RatePerThread > 0 {
+		return fmt.Errorf("target_tps and rate_per_thread cannot be used together")
+	}
+	if c.RateSchedule != nil {
+		if c.TargetTPS > 0 || c.RatePerThread > 0 {
+			return fmt.Errorf("rate_schedule cannot be used with target_tps or rate_per_thread")
+		}
+		if err := c.RateSchedule.Validate(); err != nil {
+			return err
+		}
+	}
+	switch c.LoadModel {
+	case "", "closed":
+	case "open":
+		if c.TargetTPS <= 0 && c.RatePerThread <= 0 && c.RateSchedule == nil {
+			return fmt.Errorf("open load_model requires target_tps, rate_per_thread or rate_schedule")
+		}
+	default:
+		return fmt.Errorf("unknown load_model: %s", c.LoadModel)
+	}
+	switch c.Arrival {
+	case "", "constant", "poisson":
+	default:
+		return fmt.Errorf("unknown arrival: %s", c.Arrival)
+	}
+	if c.Retry != nil {
+		if err := c.Retry.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.FindMax != nil {
+		if err := c.FindMax.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Phases != nil {
+		if c.Phases.Measure <= 0 {
+			return fmt.Errorf("phases requires a positive measure duration")
+		}
+		if c.Duration > 0 {
+			return fmt.Errorf("duration and phases cannot be used together")
+		}
+	}
+	return nil
+}
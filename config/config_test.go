@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 const sampleConfig = `{
@@ -115,3 +116,309 @@ func TestLoadConfig(t *testing.T) {
 		t.Errorf("Expected second param of second template to be array, got %s", cfg.Templates[1].Params[1].Type)
 	}
 }
+
+// loadConfigString writes content to a temporary config file and loads it.
+func loadConfigString(t *testing.T, content string) (*Config, error) {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write temp config file: %v", err)
+	}
+	return LoadConfig(configPath)
+}
+
+func TestLoadConfig_RunLimits(t *testing.T) {
+	content := `{"concurrency": 4, "duration": "5m", "max_sessions": 1000, "max_sessions_per_worker": 300}`
+	cfg, err := loadConfigString(t, content)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if time.Duration(cfg.Duration) != 5*time.Minute {
+		t.Errorf("Expected Duration 5m, got %v", time.Duration(cfg.Duration))
+	}
+	if cfg.MaxSessions != 1000 || cfg.MaxSessionsPerWorker != 300 {
+		t.Errorf("Unexpected session limits: %d, %d", cfg.MaxSessions, cfg.MaxSessionsPerWorker)
+	}
+}
+
+func TestLoadConfig_InvalidDuration(t *testing.T) {
+	if _, err := loadConfigString(t, `{"duration": "soon"}`); err == nil {
+		t.Errorf("Expected an error for an invalid duration")
+	}
+}
+
+func TestLoadConfig_Phases(t *testing.T) {
+	content := `{"phases": {"warmup": "1m", "measure": "5m", "cooldown": "30s"}}`
+	cfg, err := loadConfigString(t, content)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Phases == nil || time.Duration(cfg.Phases.Warmup) != time.Minute {
+		t.Fatalf("Unexpected phases: %+v", cfg.Phases)
+	}
+	if cfg.Phases.Total() != 6*time.Minute+30*time.Second {
+		t.Errorf("Expected total 6m30s, got %v", cfg.Phases.Total())
+	}
+}
+
+func TestLoadConfig_InvalidPhases(t *testing.T) {
+	for _, content := range []string{
+		`{"phases": {"warmup": "1m"}}`,
+		`{"duration": "10m", "phases": {"measure": "5m"}}`,
+	} {
+		if _, err := loadConfigString(t, content); err == nil {
+			t.Errorf("Expected an error for %s", content)
+		}
+	}
+}
+
+func TestLoadConfig_TargetTPSConflict(t *testing.T) {
+	if _, err := loadConfigString(t, `{"target_tps": 500, "rate_per_thread": 10}`); err == nil {
+		t.Errorf("Expected an error when both target_tps and rate_per_thread are set")
+	}
+}
+
+func TestLoadConfig_LoadModel(t *testing.T) {
+	for content, valid := range map[string]bool{
+		`{"target_tps": 100, "load_model": "open", "arrival": "poisson"}`: true,
+		`{"rate_per_thread": 10, "load_model": "closed"}`:                 true,
+		`{"load_model": "open"}`:                                          false,
+		`{"target_tps": 100, "load_model": "half-open"}`:                  false,
+		`{"target_tps": 100, "arrival": "bursty"}`:                        false,
+	} {
+		_, err := loadConfigString(t, content)
+		if valid && err != nil {
+			t.Errorf("Unexpected error for %s: %v", content, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("Expected an error for %s", content)
+		}
+	}
+}
+
+func TestLoadConfig_RateSchedule(t *testing.T) {
+	for content, valid := range map[string]bool{
+		`{"rate_schedule": {"type": "ramp", "from": 100, "to": 1000, "duration": "10m"}}`:                       true,
+		`{"rate_schedule": {"type": "step", "from": 100, "step": 100, "every": "2m", "to": 1000}}`:              true,
+		`{"rate_schedule": {"type": "sine", "base": 500, "amplitude": 300, "period": "24h"}}`:                   true,
+		`{"rate_schedule": {"type": "points", "points": [{"at": "0s", "rate": 10}, {"at": "1m", "rate": 20}]}}`: true,
+		`{"rate_schedule": {"type": "points", "points": [{"at": "1m", "rate": 10}, {"at": "0s", "rate": 20}]}}`: false,
+		`{"rate_schedule": {"type": "ramp", "from": 100, "to": 1000}}`:                                          false,
+		`{"rate_schedule": {"type": "random"}}`:                                                                 false,
+		`{"target_tps": 10, "rate_schedule": {"type": "ramp", "to": 1000, "duration": "10m"}}`:                  false,
+	} {
+		_, err := loadConfigString(t, content)
+		if valid && err != nil {
+			t.Errorf("Unexpected error for %s: %v", content, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("Expected an error for %s", content)
+		}
+	}
+}
+
+func TestLoadConfig_FindMax(t *testing.T) {
+	for content, valid := range map[string]bool{
+		`{"find_max": {"target": "concurrency", "start": 10, "step": 10, "max": 200, "hold": "1m", "max_p99": "50ms"}}`: true,
+		`{"find_max": {"target": "rate", "start": 500, "step": 250, "hold": "1m", "max_error_rate": 0.01}}`:             true,
+		`{"find_max": {"target": "rate", "start": 500, "step": 250, "hold": "1m"}}`:                                     false,
+		`{"find_max": {"target": "threads", "start": 1, "step": 1, "hold": "1m", "max_p99": "50ms"}}`:                   false,
+		`{"find_max": {"target": "rate", "start": 500, "step": 0, "hold": "1m", "max_p99": "50ms"}}`:                    false,
+		`{"find_max": {"target": "rate", "start": 0.5, "step": 0.5, "hold": "1m", "max_p99": "50ms"}}`:                  true,
+		`{"find_max": {"target": "concurrency", "start": 0.5, "step": 1, "hold": "1m", "max_p99": "50ms"}}`:             false,
+		`{"find_max": {"target": "concurrency", "start": 1, "step": 1.5, "hold": "1m", "max_p99": "50ms"}}`:             false,
+		`{"find_max": {"target": "rate", "start": 500, "step": 250, "max": 100, "hold": "1m", "max_p99": "50ms"}}`:      false,
+	} {
+		_, err := loadConfigString(t, content)
+		if valid && err != nil {
+			t.Errorf("Unexpected error for %s: %v", content, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("Expected an error for %s", content)
+		}
+	}
+}
+
+func TestLoadConfig_Transactions(t *testing.T) {
+	content := `{
+  "transactions": [
+    {"name": "point_select", "weight": 70, "templates": [{"sql": "SELECT 1", "params": []}]},
+    {"name": "update", "weight": 30, "use_transaction": true, "templates": [{"sql": "UPDATE t SET a = 1", "params": []}]}
+  ]
+}`
+	cfg, err := loadConfigString(t, content)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	txs := cfg.GetTransactions()
+	if len(txs) != 2 || txs[1].Name != "update" || !txs[1].UseTransaction {
+		t.Errorf("Unexpected transactions: %+v", txs)
+	}
+}
+
+func TestGetTransactions_Templates(t *testing.T) {
+	cfg := &Config{UseTransaction: true, Templates: []Template{{SQL: "SELECT 1"}}}
+	txs := cfg.GetTransactions()
+	if len(txs) != 1 || txs[0].Name != "" || !txs[0].UseTransaction || len(txs[0].Templates) != 1 {
+		t.Errorf("Unexpected implicit transaction: %+v", txs)
+	}
+}
+
+func TestLoadConfig_InvalidTransactions(t *testing.T) {
+	for _, content := range []string{
+		`{"templates": [{"sql": "SELECT 1"}], "transactions": [{"name": "a", "weight": 1}]}`,
+		`{"use_transaction": true, "transactions": [{"name": "a", "weight": 1}]}`,
+		`{"transactions": [{"weight": 1}]}`,
+		`{"transactions": [{"name": "a", "weight": 1}, {"name": "a", "weight": 1}]}`,
+		`{"transactions": [{"name": "a"}]}`,
+	} {
+		if _, err := loadConfigString(t, content); err == nil {
+			t.Errorf("Expected an error for %s", content)
+		}
+	}
+}
+
+func TestLoadConfig_Capture(t *testing.T) {
+	content := `{
+  "templates": [
+    {"sql": "SELECT id FROM orders WHERE user_id = ?", "params": [{"type": "number", "random_mode": "uniform", "min": 1, "max": 10}],
+     "capture": [{"column": "id", "var": "order_ids", "mode": "all"}]},
+    {"sql": "UPDATE orders SET status = 1 WHERE id IN (?)", "params": [{"type": "var", "var": "order_ids"}]}
+  ]
+}`
+	cfg, err := loadConfigString(t, content)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if c := cfg.Templates[0].Capture; len(c) != 1 || c[0].Column != "id" || c[0].Mode != "all" {
+		t.Errorf("Unexpected capture: %+v", c)
+	}
+	if v := cfg.Templates[1].Params[0].Var; v == nil || *v != "order_ids" {
+		t.Errorf("Unexpected var param: %v", v)
+	}
+}
+
+func TestLoadConfig_InvalidCapture(t *testing.T) {
+	for _, content := range []string{
+		// Variable used before it is captured.
+		`{"templates": [{"sql": "SELECT ?", "params": [{"type": "var", "var": "x"}]}, {"sql": "SELECT 1 AS x", "capture": [{"column": "x", "var": "x"}]}]}`,
+		// Variable captured by another transaction type.
+		`{"transactions": [{"name": "a", "weight": 1, "templates": [{"sql": "SELECT 1 AS x", "capture": [{"column": "x", "var": "x"}]}]},
+		                   {"name": "b", "weight": 1, "templates": [{"sql": "SELECT ?", "params": [{"type": "var", "var": "x"}]}]}]}`,
+		`{"templates": [{"sql": "SELECT ?", "params": [{"type": "var"}]}]}`,
+		`{"templates": [{"sql": "SELECT 1 AS x", "capture": [{"column": "x"}]}]}`,
+		`{"templates": [{"sql": "SELECT 1 AS x", "capture": [{"column": "x", "var": "x", "mode": "last"}]}]}`,
+		// Capture on statements run as exec.
+		`{"templates": [{"sql": "SELECT 1 AS x", "mode": "exec", "capture": [{"column": "x", "var": "x"}]}]}`,
+		`{"templates": [{"sql": "CALL new_order()", "capture": [{"column": "x", "var": "x"}]}]}`,
+	} {
+		if _, err := loadConfigString(t, content); err == nil {
+			t.Errorf("Expected an error for %s", content)
+		}
+	}
+}
+
+func TestLoadConfig_SessionVars(t *testing.T) {
+	content := `{
+  "session_vars": {"user_id": {"type": "number", "random_mode": "uniform", "min": 1, "max": 10}},
+  "templates": [
+    {"sql": "INSERT INTO orders (user_id) VALUES (?)", "params": [{"type": "var", "var": "user_id"}]},
+    {"sql": "SELECT * FROM orders WHERE user_id = ?", "params": [{"type": "var", "var": "user_id"}]}
+  ]
+}`
+	cfg, err := loadConfigString(t, content)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if _, ok := cfg.GetTransactions()[0].SessionVars["user_id"]; !ok {
+		t.Errorf("Expected session variable user_id in the implicit transaction")
+	}
+}
+
+func TestLoadConfig_InvalidSessionVars(t *testing.T) {
+	for _, content := range []string{
+		`{"session_vars": {"x": {"type": "var", "var": "y"}}, "templates": [{"sql": "SELECT 1"}]}`,
+		`{"session_vars": {"x": {"type": "number"}}, "transactions": [{"name": "a", "weight": 1, "templates": [{"sql": "SELECT 1"}]}]}`,
+		`{"transactions": [{"name": "a", "weight": 1, "session_vars": {"x": {"type": "number"}}, "templates": [{"sql": "SELECT 1"}]},
+		                   {"name": "b", "weight": 1, "templates": [{"sql": "SELECT ?", "params": [{"type": "var", "var": "x"}]}]}]}`,
+	} {
+		if _, err := loadConfigString(t, content); err == nil {
+			t.Errorf("Expected an error for %s", content)
+		}
+	}
+}
+
+func TestLoadConfig_NamedPlaceholders(t *testing.T) {
+	for content, valid := range map[string]bool{
+		`{"templates": [{"sql": "SELECT * FROM t WHERE a = :id OR b = :id", "params": [{"name": "id", "type": "number"}]}]}`: true,
+		`{"templates": [{"sql": "SELECT * FROM t WHERE a = :id", "params": [{"name": "other", "type": "number"}]}]}`:         false,
+	} {
+		if _, err := loadConfigString(t, content); (err == nil) != valid {
+			t.Errorf("Unexpected result for %s: %v", content, err)
+		}
+	}
+}
+
+func TestTemplateIsQuery(t *testing.T) {
+	for _, tc := range []struct {
+		tmpl Template
+		want bool
+	}{
+		{Template{SQL: "WITH c AS (SELECT 1) SELECT * FROM c"}, true},
+		{Template{SQL: "UPDATE t SET a = 1"}, false},
+		{Template{SQL: "CALL report()", Mode: "query"}, true},
+		{Template{SQL: "SELECT 1 INTO @x", Mode: "exec"}, false},
+	} {
+		if got := tc.tmpl.IsQuery(); got != tc.want {
+			t.Errorf("IsQuery(%q, mode %q) = %t, want %t", tc.tmpl.SQL, tc.tmpl.Mode, got, tc.want)
+		}
+	}
+}
+
+func TestLoadConfig_Retry(t *testing.T) {
+	for content, valid := range map[string]bool{
+		`{"retry": {"max_attempts": 5, "codes": [9007], "backoff": "5ms", "max_backoff": "500ms"}}`: true,
+		`{"retry": {"max_attempts": 3}}`:                                         true,
+		`{"retry": {"max_attempts": 1}}`:                                         false,
+		`{"retry": {"max_attempts": 3, "backoff": "1s", "max_backoff": "10ms"}}`: false,
+	} {
+		if _, err := loadConfigString(t, content); (err == nil) != valid {
+			t.Errorf("Unexpected result for %s: %v", content, err)
+		}
+	}
+}
+
+func TestTemplateToleratedCodes(t *testing.T) {
+	tmpl := Template{ToleratedErrors: []string{"duplicate_key", "1364"}}
+	codes, err := tmpl.ToleratedCodes()
+	if err != nil {
+		t.Fatalf("ToleratedCodes failed: %v", err)
+	}
+	if len(codes) != len(ErrorClasses["duplicate_key"])+1 || codes[len(codes)-1] != 1364 {
+		t.Errorf("Unexpected codes: %v", codes)
+	}
+
+	for _, bad := range []string{"duplicate", "-1", "70000"} {
+		tmpl := Template{ToleratedErrors: []string{bad}}
+		if _, err := tmpl.ToleratedCodes(); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestLoadConfig_TxOptions(t *testing.T) {
+	for content, valid := range map[string]bool{
+		`{"use_transaction": true, "isolation_level": "read_committed", "read_only": true, "templates": [{"sql": "SELECT 1"}]}`:              true,
+		`{"use_transaction": true, "start_transaction": "START TRANSACTION WITH CONSISTENT SNAPSHOT", "templates": [{"sql": "SELECT 1"}]}`:   true,
+		`{"transactions": [{"name": "a", "weight": 1, "use_transaction": true, "start_transaction": "BEGIN PESSIMISTIC", "templates": []}]}`: true,
+		`{"isolation_level": "read_committed", "templates": [{"sql": "SELECT 1"}]}`:                                                          false,
+		`{"use_transaction": true, "isolation_level": "snapshot", "templates": [{"sql": "SELECT 1"}]}`:                                       false,
+		`{"use_transaction": true, "start_transaction": "SELECT 1", "templates": [{"sql": "SELECT 1"}]}`:                                     false,
+		`{"use_transaction": true, "read_only": true, "start_transaction": "BEGIN", "templates": [{"sql": "SELECT 1"}]}`:                     false,
+		`{"read_only": true, "transactions": [{"name": "a", "weight": 1, "use_transaction": true, "templates": []}]}`:                        false,
+	} {
+		if _, err := loadConfigString(t, content); (err == nil) != valid {
+			t.Errorf("Unexpected result for %s: %v", content, err)
+		}
+	}
+}
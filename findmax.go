@@ -0,0 +1,139 @@
+package main
+
+import (
+	"database_workload/config"
+	"database_workload/stats"
+	"database_workload/worker"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// minRateRatio is the fraction of the target rate a step must achieve to
+// pass when searching for the maximum rate. Without it, a closed-loop run
+// that silently falls behind its target would look healthy.
+const minRateRatio = 0.9
+
+// checkFindMax checks the settings that find-max mode relies on.
+func checkFindMax(cfg *config.Config) error {
+	if cfg.FindMax == nil {
+		return fmt.Errorf("find_max is not configured")
+	}
+	if cfg.Duration > 0 || cfg.Phases != nil || cfg.MaxSessions > 0 || cfg.MaxSessionsPerWorker > 0 {
+		return fmt.Errorf("duration, phases and session limits cannot be used with find-max")
+	}
+	if cfg.FindMax.Target == "rate" {
+		if cfg.TargetTPS > 0 || cfg.RatePerThread > 0 || cfg.RateSchedule != nil {
+			return fmt.Errorf("a rate search sets the target rate itself; remove target_tps, rate_per_thread and rate_schedule")
+		}
+		if cfg.Concurrency <= 0 {
+			return fmt.Errorf("a rate search requires a positive concurrency")
+		}
+	}
+	return nil
+}
+
+// findMax increases the load step by step, holding each step, until a step
+// violates the SLO of fm or the maximum level is reached. It returns the exit
+// code and the outcome of every step.
+func findMax(workers *pool, collector *stats.Collector, fm *config.FindMax, reportInterval time.Duration, sigChan <-chan os.Signal) (int, *stats.FindMaxResult) {
+	result := &stats.FindMaxResult{Target: fm.Target}
+	if fm.Target == "rate" {
+		workers.shared.Pacer = worker.NewPacer(fm.Start, worker.PacerOptionsFrom(workers.cfg))
+		workers.grow(workers.cfg.Concurrency)
+	}
+
+	for level := fm.Start; fm.Max <= 0 || level <= fm.Max; level += fm.Step {
+		threads := workers.size
+		if fm.Target == "rate" {
+			workers.shared.Pacer.SetRate(level)
+		} else {
+			threads = int(level)
+			workers.grow(threads)
+		}
+		log.Printf("find-max: %s %g, holding for %v", fm.Target, level, time.Duration(fm.Hold))
+
+		snap, span, ok := holdStep(collector, time.Duration(fm.Hold), reportInterval, threads, sigChan)
+		if !ok {
+			log.Println("Shutdown signal received, stopping workers...")
+			return exitInterrupted, result
+		}
+
+		step := evaluateStep(fm, level, snap, span)
+		result.Steps = append(result.Steps, step)
+		log.Printf("find-max: %s %g: tps %.2f, p99 %.2fms, error rate %.4f, passed: %t",
+			fm.Target, level, step.TPS, step.P99, step.ErrorRate, step.Passed)
+		if !step.Passed {
+			break
+		}
+		result.Best = level
+	}
+
+	if result.Best == 0 {
+		log.Printf("find-max: no %s level met the SLO", fm.Target)
+		return exitSessionErrors, result
+	}
+	log.Printf("find-max: highest sustainable %s: %g", fm.Target, result.Best)
+	return exitOK, result
+}
+
+// holdStep collects measurements for at least hold, printing interval reports
+// along the way. It returns the time actually covered, which runs to the first
+// report at or after hold, and false if a signal arrives first.
+func holdStep(collector *stats.Collector, hold, reportInterval time.Duration, threads int, sigChan <-chan os.Signal) (*stats.Snapshot, time.Duration, bool) {
+	// Measurements still pending from the previous step do not count.
+	collector.Collect()
+
+	tick := hold
+	if reportInterval > 0 && reportInterval < hold {
+		tick = reportInterval
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	step := stats.NewSnapshot()
+	start := time.Now()
+	last := start
+	for {
+		select {
+		case <-sigChan:
+			return step, last.Sub(start), false
+		case now := <-ticker.C:
+			snap := collector.Collect()
+			step.Merge(snap)
+			if reportInterval > 0 {
+				stats.WriteInterval(os.Stdout, snap, now.Sub(start), now.Sub(last), threads)
+			}
+			last = now
+			if now.Sub(start) >= hold {
+				return step, last.Sub(start), true
+			}
+		}
+	}
+}
+
+// evaluateStep checks the measurements of a step, which covered span, against
+// the SLO of fm.
+func evaluateStep(fm *config.FindMax, level float64, snap *stats.Snapshot, span time.Duration) stats.StepResult {
+	p99 := snap.SessionLatency.Percentile(99)
+	step := stats.StepResult{
+		Level:  level,
+		TPS:    float64(snap.Sessions) / span.Seconds(),
+		P99:    float64(p99) / float64(time.Millisecond),
+		Passed: snap.Sessions > 0,
+	}
+	if snap.Sessions > 0 {
+		step.ErrorRate = float64(snap.SessionErrors) / float64(snap.Sessions)
+	}
+	if fm.MaxP99 > 0 && p99 > time.Duration(fm.MaxP99) {
+		step.Passed = false
+	}
+	if fm.MaxErrorRate > 0 && step.ErrorRate > fm.MaxErrorRate {
+		step.Passed = false
+	}
+	if fm.Target == "rate" && step.TPS < level*minRateRatio {
+		step.Passed = false
+	}
+	return step
+}
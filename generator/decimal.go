@@ -0,0 +1,94 @@
+package generator
+
+import (
+	"database_workload/config"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Limits of the MySQL DECIMAL type.
+	maxDecimalPrecision = 65
+	maxDecimalScale     = 30
+)
+
+// NewDecimalGenerator is a factory for decimal and float generators. The
+// value is drawn by a number generator in units of 10^-scale, so every
+// number mode is available and no rounding error is introduced.
+func NewDecimalGenerator(p *config.Param) (Generator, error) {
+	scale := 0
+	if p.Scale != nil {
+		scale = *p.Scale
+	}
+	if scale < 0 || scale > maxDecimalScale {
+		return nil, fmt.Errorf("%s scale must be between 0 and %d, got %d", p.Type, maxDecimalScale, scale)
+	}
+	limit := int64(math.MaxInt64)
+	if p.Precision != nil {
+		precision := *p.Precision
+		if precision < 1 || precision > maxDecimalPrecision {
+			return nil, fmt.Errorf("%s precision must be between 1 and %d, got %d", p.Type, maxDecimalPrecision, precision)
+		}
+		if scale > precision {
+			return nil, fmt.Errorf("%s scale (%d) cannot be greater than precision (%d)", p.Type, scale, precision)
+		}
+		// Larger precisions hold every int64.
+		if precision < 19 {
+			limit = int64(math.Pow10(precision)) - 1
+		}
+	}
+	numGen, err := NewNumberGenerator(p)
+	if err != nil {
+		return nil, err
+	}
+	return &DecimalGenerator{
+		numberGen: numGen,
+		scale:     scale,
+		limit:     limit,
+		float:     p.Type == "float",
+	}, nil
+}
+
+// DecimalGenerator scales generated numbers down by 10^scale. It produces
+// decimal strings, which MySQL stores exactly, or float64 values.
+type DecimalGenerator struct {
+	numberGen Generator
+	scale     int
+	limit     int64 // largest absolute unscaled value allowed by the precision
+	float     bool
+}
+
+func (g *DecimalGenerator) Generate() interface{} {
+	n := g.numberGen.Generate().(int64)
+	if n > g.limit {
+		n = g.limit
+	} else if n < -g.limit {
+		n = -g.limit
+	}
+	if g.float {
+		return float64(n) / math.Pow10(g.scale)
+	}
+	return formatDecimal(n, g.scale)
+}
+
+// formatDecimal formats the unscaled value n with scale digits after the
+// decimal point.
+func formatDecimal(n int64, scale int) string {
+	if scale == 0 {
+		return strconv.FormatInt(n, 10)
+	}
+	sign := ""
+	u := uint64(n)
+	if n < 0 {
+		sign = "-"
+		u = -u
+	}
+	digits := strconv.FormatUint(u, 10)
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	point := len(digits) - scale
+	return sign + digits[:point] + "." + digits[point:]
+}
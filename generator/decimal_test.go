@@ -0,0 +1,98 @@
+package generator
+
+import (
+	"database_workload/config"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFormatDecimal(t *testing.T) {
+	tests := []struct {
+		n     int64
+		scale int
+		want  string
+	}{
+		{123456, 2, "1234.56"},
+		{5, 2, "0.05"},
+		{0, 2, "0.00"},
+		{-5, 3, "-0.005"},
+		{-120, 1, "-12.0"},
+		{42, 0, "42"},
+		{math.MinInt64, 2, "-92233720368547758.08"},
+	}
+	for _, tt := range tests {
+		if got := formatDecimal(tt.n, tt.scale); got != tt.want {
+			t.Errorf("formatDecimal(%d, %d) = %q, want %q", tt.n, tt.scale, got, tt.want)
+		}
+	}
+}
+
+func TestDecimalGenerator(t *testing.T) {
+	min, max := int64(1), int64(999999)
+	precision, scale := 12, 2
+	param := &config.Param{Type: "decimal", RandomMode: "uniform", Min: &min, Max: &max, Precision: &precision, Scale: &scale}
+	gen, err := New(param)
+	if err != nil {
+		t.Fatalf("factory failed for decimal: %v", err)
+	}
+	for i := 0; i < 1000; i++ {
+		s := gen.Generate().(string)
+		dot := strings.IndexByte(s, '.')
+		if dot < 1 || len(s)-dot-1 != scale {
+			t.Fatalf("expected %d decimals, got %s", scale, s)
+		}
+		if v, err := strconv.ParseFloat(s, 64); err != nil || v < 0.01 || v > 9999.99 {
+			t.Fatalf("value %s out of range", s)
+		}
+	}
+}
+
+func TestFloatGenerator(t *testing.T) {
+	value := int64(1999)
+	scale := 2
+	param := &config.Param{Type: "float", RandomMode: "fixed", Value: &value, Scale: &scale}
+	gen, err := New(param)
+	if err != nil {
+		t.Fatalf("factory failed for float: %v", err)
+	}
+	if v := gen.Generate().(float64); v != 19.99 {
+		t.Errorf("expected 19.99, got %v", v)
+	}
+}
+
+func TestDecimalGenerator_Precision(t *testing.T) {
+	mean, stddev := 0.0, 1e6
+	precision, scale := 4, 2
+	param := &config.Param{Type: "decimal", RandomMode: "normal", Mean: &mean, Stddev: &stddev, Precision: &precision, Scale: &scale}
+	gen, err := New(param)
+	if err != nil {
+		t.Fatalf("factory failed for decimal: %v", err)
+	}
+	for i := 0; i < 1000; i++ {
+		s := strings.TrimPrefix(gen.Generate().(string), "-")
+		if len(s) > len("99.99") {
+			t.Fatalf("value %s exceeds precision %d", s, precision)
+		}
+	}
+}
+
+func TestDecimalGenerator_Invalid(t *testing.T) {
+	min, max := int64(1), int64(100)
+	neg, big, three, two := -1, 66, 3, 2
+	tests := []struct {
+		name  string
+		param *config.Param
+	}{
+		{"negative scale", &config.Param{Type: "decimal", RandomMode: "uniform", Min: &min, Max: &max, Scale: &neg}},
+		{"precision too large", &config.Param{Type: "decimal", RandomMode: "uniform", Min: &min, Max: &max, Precision: &big}},
+		{"scale above precision", &config.Param{Type: "decimal", RandomMode: "uniform", Min: &min, Max: &max, Precision: &two, Scale: &three}},
+		{"bad number mode", &config.Param{Type: "float", RandomMode: "uniform"}},
+	}
+	for _, tt := range tests {
+		if _, err := New(tt.param); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package generator
+
+import (
+	"database_workload/config"
+	"fmt"
+	"math"
+	"time"
+)
+
+// driftEpoch is the common time origin of all drifting generators, so that
+// the workers of a run move their hot regions together.
+var driftEpoch = time.Now()
+
+// DriftGenerator shifts the values of another number generator across
+// [min, max] as time passes, wrapping around at max.
+type DriftGenerator struct {
+	inner  Generator
+	min    int64
+	items  int64
+	period time.Duration
+	every  time.Duration
+	jump   float64
+	now    func() time.Time
+}
+
+func newDriftGenerator(inner Generator, min, max int64, d *config.Drift) (*DriftGenerator, error) {
+	if min > max {
+		return nil, fmt.Errorf("min (%d) cannot be greater than max (%d)", min, max)
+	}
+	if (d.Period > 0) == (d.Every > 0) {
+		return nil, fmt.Errorf("drift requires either period or every")
+	}
+	if d.Jump < 0 || d.Jump >= 1 {
+		// A jump of the whole range would wrap around to where it started.
+		return nil, fmt.Errorf("drift jump must be in [0, 1), got %g", d.Jump)
+	}
+	return &DriftGenerator{
+		inner:  inner,
+		min:    min,
+		items:  max - min + 1,
+		period: time.Duration(d.Period),
+		every:  time.Duration(d.Every),
+		jump:   d.Jump,
+		now:    time.Now,
+	}, nil
+}
+
+// shift returns how far the values are moved at the current time.
+func (g *DriftGenerator) shift() int64 {
+	elapsed := g.now().Sub(driftEpoch)
+	if g.period > 0 {
+		frac := math.Mod(float64(elapsed)/float64(g.period), 1)
+		return int64(frac * float64(g.items))
+	}
+	jumps := int64(elapsed / g.every)
+	if g.jump == 0 {
+		// A random position, but the same one for every worker.
+		if jumps == 0 {
+			return 0
+		}
+		return int64(fnv64(uint64(jumps)) % uint64(g.items))
+	}
+	return int64(math.Mod(float64(jumps)*g.jump, 1) * float64(g.items))
+}
+
+func (g *DriftGenerator) Generate() interface{} {
+	v := g.inner.Generate().(int64)
+	offset := (v - g.min + g.shift()) % g.items
+	if offset < 0 {
+		offset += g.items
+	}
+	return g.min + offset
+}
@@ -22,6 +22,8 @@ func New(p *config.Param) (Generator, error) {
 	switch p.Type {
 	case "number":
 		return NewNumberGenerator(p)
+	case "decimal", "float":
+		return NewDecimalGenerator(p)
 	case "string":
 		return NewStringGenerator(p)
 	case "date":
@@ -0,0 +1,58 @@
+package generator
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// HotspotGenerator generates numbers in [min, max] where a fraction of the
+// values, the hot set, receives a given share of the accesses. Values are
+// uniform within the hot set and within the rest of the range.
+type HotspotGenerator struct {
+	min        int64
+	items      int64
+	hotMin     int64
+	hotItems   int64
+	accessFrac float64
+}
+
+func newHotspotGenerator(min, max int64, dataFrac, accessFrac, offset float64) (*HotspotGenerator, error) {
+	if min > max {
+		return nil, fmt.Errorf("min (%d) cannot be greater than max (%d)", min, max)
+	}
+	if dataFrac <= 0 || dataFrac > 1 {
+		return nil, fmt.Errorf("hot_data_fraction must be in (0, 1], got %g", dataFrac)
+	}
+	if accessFrac < 0 || accessFrac > 1 {
+		return nil, fmt.Errorf("hot_access_fraction must be in [0, 1], got %g", accessFrac)
+	}
+	if offset < 0 || offset > 1 {
+		return nil, fmt.Errorf("hot_offset must be in [0, 1], got %g", offset)
+	}
+	items := max - min + 1
+	hotItems := int64(math.Round(float64(items) * dataFrac))
+	if hotItems < 1 {
+		hotItems = 1
+	}
+	return &HotspotGenerator{
+		min:        min,
+		items:      items,
+		hotMin:     min + int64(offset*float64(items-hotItems)),
+		hotItems:   hotItems,
+		accessFrac: accessFrac,
+	}, nil
+}
+
+func (g *HotspotGenerator) Generate() interface{} {
+	cold := g.items - g.hotItems
+	if cold == 0 || rand.Float64() < g.accessFrac {
+		return g.hotMin + rand.Int63n(g.hotItems)
+	}
+	// Uniform over the range with the hot set cut out.
+	v := g.min + rand.Int63n(cold)
+	if v >= g.hotMin {
+		v += g.hotItems
+	}
+	return v
+}
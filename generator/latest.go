@@ -0,0 +1,103 @@
+package generator
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+)
+
+// sequence is a counter shared by all generators of the same name.
+type sequence struct {
+	started atomic.Bool  // whether the start was set
+	next    atomic.Int64 // next value to hand out
+	used    atomic.Bool  // whether any value was handed out
+}
+
+var (
+	sequencesMu sync.Mutex
+	sequences   = make(map[string]*sequence)
+)
+
+// getSequence returns the sequence of the given name, creating it if needed.
+func getSequence(name string) *sequence {
+	sequencesMu.Lock()
+	defer sequencesMu.Unlock()
+	s, ok := sequences[name]
+	if !ok {
+		s = &sequence{}
+		sequences[name] = s
+	}
+	return s
+}
+
+// start sets the first value of s, unless it was set already.
+func (s *sequence) start(v int64) {
+	if s.started.CompareAndSwap(false, true) {
+		s.next.Store(v)
+	}
+}
+
+// last returns the last value handed out, if any.
+func (s *sequence) last() (int64, bool) {
+	if !s.used.Load() {
+		return 0, false
+	}
+	return s.next.Load() - 1, true
+}
+
+// SequenceGenerator generates increasing numbers from a sequence shared by
+// all workers, e.g. the ids of inserted rows.
+type SequenceGenerator struct {
+	seq *sequence
+}
+
+func (g *SequenceGenerator) Generate() interface{} {
+	v := g.seq.next.Add(1) - 1
+	g.seq.used.Store(true)
+	return v
+}
+
+// LatestGenerator generates numbers in [min, latest] that favour the latest
+// values, as the "latest" distribution of YCSB workload D: latest, then
+// latest-1, and so on, following a Zipfian distribution. latest is the last
+// value of the sequence, or max while the sequence has not exceeded it.
+type LatestGenerator struct {
+	min  int64
+	max  int64 // math.MinInt64 if unset
+	seq  *sequence
+	zipf *ZipfianGenerator
+}
+
+func newLatestGenerator(min int64, max *int64, seqName *string, theta float64) (*LatestGenerator, error) {
+	g := &LatestGenerator{min: min, max: math.MinInt64}
+	if max != nil {
+		if min > *max {
+			return nil, fmt.Errorf("min (%d) cannot be greater than max (%d)", min, *max)
+		}
+		g.max = *max
+	}
+	if seqName != nil {
+		g.seq = getSequence(*seqName)
+	}
+	var err error
+	if g.zipf, err = newZipfianGenerator(0, 0, theta); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (g *LatestGenerator) Generate() interface{} {
+	latest := g.max
+	if g.seq != nil {
+		if last, ok := g.seq.last(); ok && last > latest {
+			latest = last
+		}
+	}
+	if latest < g.min {
+		// Nothing generated yet and no max: only min exists.
+		return g.min
+	}
+	g.zipf.resize(latest - g.min + 1)
+	return latest - g.zipf.rank()
+}
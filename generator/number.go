@@ -9,7 +9,27 @@ import (
 
 // NewNumberGenerator is a factory for creating number generators from config.
 func NewNumberGenerator(p *config.Param) (Generator, error) {
+	g, err := newNumberGenerator(p)
+	if err != nil || p.Drift == nil {
+		return g, err
+	}
+	switch p.RandomMode {
+	case "fixed", "sequence", "latest":
+		return nil, fmt.Errorf("drift cannot be used with %s mode", p.RandomMode)
+	}
+	if p.Min == nil || p.Max == nil {
+		return nil, fmt.Errorf("drift requires min and max")
+	}
+	return newDriftGenerator(g, *p.Min, *p.Max, p.Drift)
+}
+
+func newNumberGenerator(p *config.Param) (Generator, error) {
 	switch p.RandomMode {
+	case "fixed":
+		if p.Value == nil {
+			return nil, fmt.Errorf("fixed mode requires value")
+		}
+		return &FixedGenerator{value: *p.Value}, nil
 	case "uniform":
 		if p.Min == nil || p.Max == nil {
 			return nil, fmt.Errorf("uniform mode requires min and max")
@@ -25,11 +45,78 @@ func NewNumberGenerator(p *config.Param) (Generator, error) {
 			return nil, fmt.Errorf("partition_power_law mode requires min, max, exponent, and partition")
 		}
 		return newPartitionedPowerLawGenerator(*p.Min, *p.Max, *p.Partition, *p.Exponent)
+	case "zipfian", "scrambled_zipfian":
+		if p.Min == nil || p.Max == nil {
+			return nil, fmt.Errorf("%s mode requires min and max", p.RandomMode)
+		}
+		theta := defaultZipfianTheta
+		if p.Theta != nil {
+			theta = *p.Theta
+		}
+		z, err := newZipfianGenerator(*p.Min, *p.Max, theta)
+		if err != nil {
+			return nil, err
+		}
+		if p.RandomMode == "scrambled_zipfian" {
+			return &ScrambledZipfianGenerator{zipf: z}, nil
+		}
+		return z, nil
+	case "hotspot":
+		if p.Min == nil || p.Max == nil || p.HotDataFraction == nil || p.HotAccessFraction == nil {
+			return nil, fmt.Errorf("hotspot mode requires min, max, hot_data_fraction and hot_access_fraction")
+		}
+		offset := 0.0
+		if p.HotOffset != nil {
+			offset = *p.HotOffset
+		}
+		return newHotspotGenerator(*p.Min, *p.Max, *p.HotDataFraction, *p.HotAccessFraction, offset)
+	case "sequence":
+		if p.Sequence == nil {
+			return nil, fmt.Errorf("sequence mode requires sequence")
+		}
+		start := int64(1)
+		if p.Min != nil {
+			start = *p.Min
+		}
+		seq := getSequence(*p.Sequence)
+		seq.start(start)
+		return &SequenceGenerator{seq: seq}, nil
+	case "latest":
+		if p.Min == nil || (p.Max == nil && p.Sequence == nil) {
+			return nil, fmt.Errorf("latest mode requires min, and max or sequence")
+		}
+		theta := defaultZipfianTheta
+		if p.Theta != nil {
+			theta = *p.Theta
+		}
+		return newLatestGenerator(*p.Min, p.Max, p.Sequence, theta)
+	case "exponential":
+		if p.Mean == nil {
+			return nil, fmt.Errorf("exponential mode requires mean")
+		}
+		return newExponentialGenerator(*p.Mean, p.Min, p.Max)
+	case "normal", "lognormal":
+		if p.Mean == nil || p.Stddev == nil {
+			return nil, fmt.Errorf("%s mode requires mean and stddev", p.RandomMode)
+		}
+		if p.RandomMode == "normal" {
+			return newNormalGenerator(*p.Mean, *p.Stddev, p.Min, p.Max)
+		}
+		return newLogNormalGenerator(*p.Mean, *p.Stddev, p.Min, p.Max)
 	default:
 		return nil, fmt.Errorf("unknown number random_mode: %s", p.RandomMode)
 	}
 }
 
+// FixedGenerator always generates the same number.
+type FixedGenerator struct {
+	value int64
+}
+
+func (g *FixedGenerator) Generate() interface{} {
+	return g.value
+}
+
 // UniformGenerator generates a number uniformly in a given range.
 type UniformGenerator struct {
 	min int64
@@ -135,3 +222,70 @@ func (g *PartitionedPowerLawGenerator) Generate() interface{} {
 	}
 	return gen.Generate()
 }
+
+// ContinuousGenerator rounds samples of a continuous distribution to
+// numbers, clamped to min and max if they are set.
+type ContinuousGenerator struct {
+	sample func() float64
+	min    int64
+	max    int64
+}
+
+func newContinuousGenerator(sample func() float64, min, max *int64) (*ContinuousGenerator, error) {
+	g := &ContinuousGenerator{sample: sample, min: math.MinInt64, max: math.MaxInt64}
+	if min != nil {
+		g.min = *min
+	}
+	if max != nil {
+		g.max = *max
+	}
+	if g.min > g.max {
+		return nil, fmt.Errorf("min (%d) cannot be greater than max (%d)", g.min, g.max)
+	}
+	return g, nil
+}
+
+func (g *ContinuousGenerator) Generate() interface{} {
+	return clamp(math.Round(g.sample()), g.min, g.max)
+}
+
+// newExponentialGenerator follows an exponential distribution with the
+// given mean.
+func newExponentialGenerator(mean float64, min, max *int64) (*ContinuousGenerator, error) {
+	if mean <= 0 {
+		return nil, fmt.Errorf("exponential mean must be positive, got %g", mean)
+	}
+	return newContinuousGenerator(func() float64 { return rand.ExpFloat64() * mean }, min, max)
+}
+
+// newNormalGenerator follows a normal (Gaussian) distribution.
+func newNormalGenerator(mean, stddev float64, min, max *int64) (*ContinuousGenerator, error) {
+	if stddev <= 0 {
+		return nil, fmt.Errorf("normal stddev must be positive, got %g", stddev)
+	}
+	return newContinuousGenerator(func() float64 { return rand.NormFloat64()*stddev + mean }, min, max)
+}
+
+// newLogNormalGenerator follows a log-normal distribution whose values have
+// the given mean and standard deviation.
+func newLogNormalGenerator(mean, stddev float64, min, max *int64) (*ContinuousGenerator, error) {
+	if mean <= 0 || stddev <= 0 {
+		return nil, fmt.Errorf("lognormal mean and stddev must be positive, got %g and %g", mean, stddev)
+	}
+	// Parameters of the underlying normal distribution.
+	sigma2 := math.Log(1 + stddev*stddev/(mean*mean))
+	mu := math.Log(mean) - sigma2/2
+	sigma := math.Sqrt(sigma2)
+	return newContinuousGenerator(func() float64 { return math.Exp(rand.NormFloat64()*sigma + mu) }, min, max)
+}
+
+// clamp converts v to an int64 within [min, max].
+func clamp(v float64, min, max int64) int64 {
+	if v <= float64(min) {
+		return min
+	}
+	if v >= float64(max) {
+		return max
+	}
+	return int64(v)
+}
@@ -2,7 +2,10 @@ package generator
 
 import (
 	"database_workload/config"
+	"fmt"
+	"math"
 	"testing"
+	"time"
 )
 
 func TestUniformGenerator(t *testing.T) {
@@ -71,3 +74,333 @@ func TestPartitionedPowerLawGenerator(t *testing.T) {
 		}
 	}
 }
+
+func TestFixedGenerator(t *testing.T) {
+	value := int64(42)
+	gen, err := New(&config.Param{Type: "number", RandomMode: "fixed", Value: &value})
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	if val := gen.Generate().(int64); val != 42 {
+		t.Errorf("expected 42, got %d", val)
+	}
+}
+
+func TestExponentialGenerator(t *testing.T) {
+	mean := 100.0
+	gen, err := New(&config.Param{Type: "number", RandomMode: "exponential", Mean: &mean})
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	var sum int64
+	n := 100000
+	for i := 0; i < n; i++ {
+		val := gen.Generate().(int64)
+		if val < 0 {
+			t.Fatalf("generated negative value %d", val)
+		}
+		sum += val
+	}
+	if avg := float64(sum) / float64(n); avg < 95 || avg > 105 {
+		t.Errorf("expected a mean close to 100, got %.2f", avg)
+	}
+}
+
+func TestExponentialGenerator_Clamped(t *testing.T) {
+	mean := 100.0
+	min, max := int64(10), int64(50)
+	gen, err := New(&config.Param{Type: "number", RandomMode: "exponential", Mean: &mean, Min: &min, Max: &max})
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	for i := 0; i < 1000; i++ {
+		if val := gen.Generate().(int64); val < 10 || val > 50 {
+			t.Fatalf("generated value %d is out of range [10, 50]", val)
+		}
+	}
+
+	zero := 0.0
+	if _, err := New(&config.Param{Type: "number", RandomMode: "exponential", Mean: &zero}); err == nil {
+		t.Errorf("expected an error for a zero mean")
+	}
+}
+
+func TestZipfianGenerator(t *testing.T) {
+	min, max, theta := int64(1), int64(1000), 0.99
+	gen, err := New(&config.Param{Type: "number", RandomMode: "zipfian", Min: &min, Max: &max, Theta: &theta})
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	counts := make(map[int64]int)
+	n := 200000
+	for i := 0; i < n; i++ {
+		val := gen.Generate().(int64)
+		if val < min || val > max {
+			t.Fatalf("generated value %d is out of range [%d, %d]", val, min, max)
+		}
+		counts[val]++
+	}
+	// The most popular value has probability 1/zeta(n, theta), the second
+	// 1/2^theta of that.
+	want := float64(n) / zeta(1000, theta)
+	if got := float64(counts[1]); got < want*0.95 || got > want*1.05 {
+		t.Errorf("expected about %.0f occurrences of 1, got %.0f", want, got)
+	}
+	if !(counts[1] > counts[2] && counts[2] > counts[3] && counts[3] > counts[100]) {
+		t.Errorf("expected popularity to decrease with the value: %d %d %d %d", counts[1], counts[2], counts[3], counts[100])
+	}
+}
+
+func TestZipfianGenerator_Invalid(t *testing.T) {
+	min, max := int64(1), int64(1000)
+	for _, theta := range []float64{0, 1, 1.5} {
+		th := theta
+		if _, err := New(&config.Param{Type: "number", RandomMode: "zipfian", Min: &min, Max: &max, Theta: &th}); err == nil {
+			t.Errorf("expected an error for theta %g", theta)
+		}
+	}
+	if _, err := New(&config.Param{Type: "number", RandomMode: "zipfian", Min: &min}); err == nil {
+		t.Errorf("expected an error without max")
+	}
+}
+
+func TestZeta(t *testing.T) {
+	// The approximated tail must match the exact sum.
+	n, theta := 50000.0, 0.99
+	exact := 0.0
+	for i := 1.0; i <= n; i++ {
+		exact += math.Pow(i, -theta)
+	}
+	if got := zeta(n, theta); math.Abs(got-exact) > 1e-9*exact {
+		t.Errorf("zeta(%g, %g) = %v, want %v", n, theta, got, exact)
+	}
+}
+
+func TestScrambledZipfianGenerator(t *testing.T) {
+	min, max := int64(1000), int64(1999)
+	gen, err := New(&config.Param{Type: "number", RandomMode: "scrambled_zipfian", Min: &min, Max: &max})
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	counts := make(map[int64]int)
+	n := 200000
+	for i := 0; i < n; i++ {
+		val := gen.Generate().(int64)
+		if val < min || val > max {
+			t.Fatalf("generated value %d is out of range [%d, %d]", val, min, max)
+		}
+		counts[val]++
+	}
+	// The hottest value keeps the Zipfian probability, but is no longer min.
+	hottest, top := int64(0), 0
+	for v, c := range counts {
+		if c > top {
+			hottest, top = v, c
+		}
+	}
+	want := float64(n) / zeta(1000, defaultZipfianTheta)
+	if float64(top) < want*0.95 {
+		t.Errorf("expected the hottest value to occur about %.0f times, got %d", want, top)
+	}
+	if hottest == min {
+		t.Errorf("expected the hottest value to be scrambled away from min")
+	}
+}
+
+func TestHotspotGenerator(t *testing.T) {
+	min, max := int64(1), int64(1000)
+	data, access, offset := 0.1, 0.9, 0.5
+	gen, err := New(&config.Param{Type: "number", RandomMode: "hotspot", Min: &min, Max: &max,
+		HotDataFraction: &data, HotAccessFraction: &access, HotOffset: &offset})
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	// 100 hot values, starting halfway through the 900 other positions.
+	hotMin, hotMax := int64(451), int64(550)
+	hot, n := 0, 100000
+	for i := 0; i < n; i++ {
+		val := gen.Generate().(int64)
+		if val < min || val > max {
+			t.Fatalf("generated value %d is out of range [%d, %d]", val, min, max)
+		}
+		if val >= hotMin && val <= hotMax {
+			hot++
+		}
+	}
+	if ratio := float64(hot) / float64(n); ratio < 0.88 || ratio > 0.92 {
+		t.Errorf("expected about 90%% of values in the hot set, got %.3f", ratio)
+	}
+
+	bad := 1.5
+	if _, err := New(&config.Param{Type: "number", RandomMode: "hotspot", Min: &min, Max: &max,
+		HotDataFraction: &bad, HotAccessFraction: &access}); err == nil {
+		t.Errorf("expected an error for hot_data_fraction 1.5")
+	}
+}
+
+func TestSequenceGenerator(t *testing.T) {
+	// Sequences are global: use a fresh name for every run.
+	name, start := fmt.Sprintf("test_sequence_%d", time.Now().UnixNano()), int64(100)
+	p := &config.Param{Type: "number", RandomMode: "sequence", Min: &start, Sequence: &name}
+	g1, err := New(p)
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	g2, _ := New(p)
+	if v := g1.Generate().(int64); v != 100 {
+		t.Errorf("expected 100, got %d", v)
+	}
+	// Generators of the same sequence share it.
+	if v := g2.Generate().(int64); v != 101 {
+		t.Errorf("expected 101, got %d", v)
+	}
+}
+
+func TestLatestGenerator(t *testing.T) {
+	name, min, max := fmt.Sprintf("test_latest_%d", time.Now().UnixNano()), int64(1), int64(1000)
+	latest, err := New(&config.Param{Type: "number", RandomMode: "latest", Min: &min, Max: &max, Sequence: &name})
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	start := max + 1
+	insert, _ := New(&config.Param{Type: "number", RandomMode: "sequence", Min: &start, Sequence: &name})
+
+	countTop := func(top int64) float64 {
+		n := 0
+		for i := 0; i < 10000; i++ {
+			val := latest.Generate().(int64)
+			if val < min || val > top {
+				t.Fatalf("generated value %d is out of range [%d, %d]", val, min, top)
+			}
+			if val > top-10 {
+				n++
+			}
+		}
+		return float64(n) / 10000
+	}
+	// Before any insert, max is the latest value.
+	if ratio := countTop(max); ratio < 0.3 {
+		t.Errorf("expected the 10 latest values to be the most popular, got %.3f", ratio)
+	}
+	for i := 0; i < 500; i++ {
+		insert.Generate()
+	}
+	if ratio := countTop(1500); ratio < 0.3 {
+		t.Errorf("expected the 10 latest inserted values to be the most popular, got %.3f", ratio)
+	}
+}
+
+func TestDriftGenerator(t *testing.T) {
+	min, max, value := int64(1), int64(100), int64(1)
+	gen, err := New(&config.Param{Type: "number", RandomMode: "uniform", Min: &min, Max: &value})
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+
+	// Continuous: the hot value 1 moves across the whole range in 100s.
+	d, err := newDriftGenerator(gen, min, max, &config.Drift{Period: config.Duration(100 * time.Second)})
+	if err != nil {
+		t.Fatalf("failed to create drift generator: %v", err)
+	}
+	for elapsed, want := range map[time.Duration]int64{0: 1, 10 * time.Second: 11, 99 * time.Second: 100, 130 * time.Second: 31} {
+		d.now = func() time.Time { return driftEpoch.Add(elapsed) }
+		if got := d.Generate().(int64); got != want {
+			t.Errorf("after %v: expected %d, got %d", elapsed, want, got)
+		}
+	}
+
+	// Jumps: a quarter of the range every minute.
+	d, err = newDriftGenerator(gen, min, max, &config.Drift{Every: config.Duration(time.Minute), Jump: 0.25})
+	if err != nil {
+		t.Fatalf("failed to create drift generator: %v", err)
+	}
+	for elapsed, want := range map[time.Duration]int64{59 * time.Second: 1, 61 * time.Second: 26, 5 * time.Minute: 26} {
+		d.now = func() time.Time { return driftEpoch.Add(elapsed) }
+		if got := d.Generate().(int64); got != want {
+			t.Errorf("after %v: expected %d, got %d", elapsed, want, got)
+		}
+	}
+}
+
+func TestDriftGenerator_Invalid(t *testing.T) {
+	min, max := int64(1), int64(100)
+	for _, drift := range []*config.Drift{
+		{},
+		{Period: config.Duration(time.Minute), Every: config.Duration(time.Minute)},
+		{Every: config.Duration(time.Minute), Jump: 1},
+		{Every: config.Duration(time.Minute), Jump: 2},
+	} {
+		if _, err := New(&config.Param{Type: "number", RandomMode: "uniform", Min: &min, Max: &max, Drift: drift}); err == nil {
+			t.Errorf("expected an error for %+v", drift)
+		}
+	}
+	value := int64(1)
+	if _, err := New(&config.Param{Type: "number", RandomMode: "fixed", Value: &value, Drift: &config.Drift{Period: config.Duration(time.Minute)}}); err == nil {
+		t.Errorf("expected an error for a drifting fixed value")
+	}
+}
+
+func TestContinuousDistributions(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	for _, tc := range []struct {
+		param      config.Param
+		mean, sdev float64
+	}{
+		{config.Param{RandomMode: "exponential", Mean: f(1000)}, 1000, 1000},
+		{config.Param{RandomMode: "normal", Mean: f(5000), Stddev: f(800)}, 5000, 800},
+		{config.Param{RandomMode: "lognormal", Mean: f(2000), Stddev: f(1500)}, 2000, 1500},
+	} {
+		p := tc.param
+		p.Type = "number"
+		gen, err := New(&p)
+		if err != nil {
+			t.Fatalf("%s: failed to create generator: %v", p.RandomMode, err)
+		}
+		n := 200000
+		var sum, sumSq float64
+		for i := 0; i < n; i++ {
+			v := float64(gen.Generate().(int64))
+			if p.RandomMode != "normal" && v < 0 {
+				t.Fatalf("%s: generated negative value %v", p.RandomMode, v)
+			}
+			sum += v
+			sumSq += v * v
+		}
+		mean := sum / float64(n)
+		sdev := math.Sqrt(sumSq/float64(n) - mean*mean)
+		if math.Abs(mean-tc.mean) > 0.02*tc.mean {
+			t.Errorf("%s: expected mean %v, got %.1f", p.RandomMode, tc.mean, mean)
+		}
+		if math.Abs(sdev-tc.sdev) > 0.05*tc.sdev {
+			t.Errorf("%s: expected stddev %v, got %.1f", p.RandomMode, tc.sdev, sdev)
+		}
+	}
+}
+
+func TestNormalGenerator_Clamped(t *testing.T) {
+	mean, stddev := 50.0, 100.0
+	min, max := int64(0), int64(100)
+	gen, err := New(&config.Param{Type: "number", RandomMode: "normal", Mean: &mean, Stddev: &stddev, Min: &min, Max: &max})
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	for i := 0; i < 1000; i++ {
+		if val := gen.Generate().(int64); val < 0 || val > 100 {
+			t.Fatalf("generated value %d is out of range [0, 100]", val)
+		}
+	}
+}
+
+func TestContinuousDistributions_Invalid(t *testing.T) {
+	mean, zero, negative := 10.0, 0.0, -1.0
+	for _, p := range []*config.Param{
+		{Type: "number", RandomMode: "normal", Mean: &mean},
+		{Type: "number", RandomMode: "normal", Mean: &mean, Stddev: &zero},
+		{Type: "number", RandomMode: "lognormal", Mean: &negative, Stddev: &mean},
+		{Type: "number", RandomMode: "exponential", Mean: &negative},
+	} {
+		if _, err := New(p); err == nil {
+			t.Errorf("expected an error for %s", p.RandomMode)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package generator
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// defaultZipfianTheta is the skew used by YCSB.
+const defaultZipfianTheta = 0.99
+
+// zetaExactTerms is the number of terms of the zeta sum computed exactly;
+// the rest is approximated, so that large ranges stay cheap to set up.
+const zetaExactTerms = 10000
+
+// ZipfianGenerator generates numbers in [min, max] following the Zipfian
+// distribution of YCSB (Gray et al., "Quickly Generating Billion-Record
+// Synthetic Databases"): min is the most popular value, min+1 the second
+// most popular, and so on.
+type ZipfianGenerator struct {
+	min   int64
+	items float64
+	theta float64
+	alpha float64
+	zeta2 float64
+	zetan float64
+	eta   float64
+}
+
+func newZipfianGenerator(min, max int64, theta float64) (*ZipfianGenerator, error) {
+	if min > max {
+		return nil, fmt.Errorf("min (%d) cannot be greater than max (%d)", min, max)
+	}
+	if theta <= 0 || theta >= 1 {
+		return nil, fmt.Errorf("zipfian theta must be in (0, 1), got %g", theta)
+	}
+	g := &ZipfianGenerator{
+		min:   min,
+		theta: theta,
+		alpha: 1 / (1 - theta),
+		zeta2: zeta(2, theta),
+	}
+	g.resize(max - min + 1)
+	return g, nil
+}
+
+// resize changes the number of values to n. Growing by a few values is
+// cheap, so that a range that grows with every insert can follow it.
+func (g *ZipfianGenerator) resize(n int64) {
+	items := float64(n)
+	if items == g.items {
+		return
+	}
+	if items > g.items && g.items > 0 && items-g.items <= zetaExactTerms {
+		for i := g.items + 1; i <= items; i++ {
+			g.zetan += math.Pow(i, -g.theta)
+		}
+	} else {
+		g.zetan = zeta(items, g.theta)
+	}
+	g.items = items
+	g.eta = (1 - math.Pow(2/items, 1-g.theta)) / (1 - g.zeta2/g.zetan)
+}
+
+// rank returns the 0-based popularity rank of the next value.
+func (g *ZipfianGenerator) rank() int64 {
+	u := rand.Float64()
+	uz := u * g.zetan
+	if uz < 1 {
+		return 0
+	}
+	if uz < 1+math.Pow(0.5, g.theta) && g.items > 1 {
+		return 1
+	}
+	r := int64(g.items * math.Pow(g.eta*u-g.eta+1, g.alpha))
+	if r >= int64(g.items) {
+		r = int64(g.items) - 1
+	}
+	return r
+}
+
+func (g *ZipfianGenerator) Generate() interface{} {
+	return g.min + g.rank()
+}
+
+// zeta returns the sum of 1/i^theta for i from 1 to n. Beyond
+// zetaExactTerms the tail is approximated with the Euler-Maclaurin formula,
+// whose error is negligible there.
+func zeta(n, theta float64) float64 {
+	sum := 0.0
+	exact := math.Min(n, zetaExactTerms)
+	for i := 1.0; i <= exact; i++ {
+		sum += math.Pow(i, -theta)
+	}
+	if n <= exact {
+		return sum
+	}
+	f := func(x float64) float64 { return math.Pow(x, -theta) }
+	df := func(x float64) float64 { return -theta * math.Pow(x, -theta-1) }
+	k := exact
+	integral := (math.Pow(n, 1-theta) - math.Pow(k, 1-theta)) / (1 - theta)
+	return sum + integral + (f(n)-f(k))/2 + (df(n)-df(k))/12
+}
+
+// ScrambledZipfianGenerator follows the same popularity distribution as
+// ZipfianGenerator, but spreads the popular values across [min, max] by
+// hashing their rank, as YCSB does, instead of packing them at min. Hash
+// collisions leave some values unused.
+type ScrambledZipfianGenerator struct {
+	zipf *ZipfianGenerator
+}
+
+func (g *ScrambledZipfianGenerator) Generate() interface{} {
+	h := fnv64(uint64(g.zipf.rank()))
+	return g.zipf.min + int64(h%uint64(g.zipf.items))
+}
+
+// fnv64 is the 64-bit FNV-1a hash of the bytes of v, as used by YCSB.
+func fnv64(v uint64) uint64 {
+	const (
+		offset = 14695981039346656037
+		prime  = 1099511628211
+	)
+	h := uint64(offset)
+	for i := 0; i < 8; i++ {
+		h ^= v & 0xff
+		h *= prime
+		v >>= 8
+	}
+	return h
+}
@@ -3,48 +3,245 @@ package main
 import (
 	"context"
 	"database_workload/config"
+	"database_workload/metrics"
+	"database_workload/stats"
 	"database_workload/worker"
 	"flag"
 	"log"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"sync"
 	"syscall"
+	"time"
+)
+
+// Exit codes. log.Fatalf exits with 1 on configuration and setup errors.
+const (
+	exitOK            = 0
+	exitSessionErrors = 2   // the run completed but some sessions failed, or find-max found no level meeting the SLO
+	exitInterrupted   = 130 // a run with a duration or session limit was stopped by a signal
 )
 
 func main() {
 	configPath := flag.String("config", "config.json", "Path to the configuration file")
+	reportInterval := flag.Int("report-interval", 10, "Print interval statistics every N seconds (0 to disable)")
+	reportPath := flag.String("report", "", "Write the end-of-run summary to this file (Markdown for .md, JSON otherwise)")
+	metricsAddr := flag.String("metrics-addr", "", "Serve Prometheus metrics on this address (e.g. :9090)")
+	duration := flag.Duration("duration", 0, "Stop the run after this long (overrides duration in the config)")
+	maxSessions := flag.Int64("max-sessions", 0, "Stop after this many sessions across all workers (overrides max_sessions)")
+	maxSessionsPerWorker := flag.Int64("max-sessions-per-worker", 0, "Stop each worker after this many sessions (overrides max_sessions_per_worker)")
+	mode := flag.String("mode", "run", `Run mode: "run", or "find-max" to search for the highest load meeting the find_max SLO`)
 	flag.Parse()
 
 	cfg, err := config.LoadConfig(*configPath)
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
-
-	log.Printf("Starting workload with concurrency %d", cfg.Concurrency)
+	if *duration > 0 {
+		if cfg.Phases != nil {
+			log.Fatalf("-duration cannot be used with phases in the configuration")
+		}
+		cfg.Duration = config.Duration(*duration)
+	}
+	if *maxSessions > 0 {
+		cfg.MaxSessions = *maxSessions
+	}
+	if *maxSessionsPerWorker > 0 {
+		cfg.MaxSessionsPerWorker = *maxSessionsPerWorker
+	}
+	// Checked after the flag overrides, which find-max mode rejects too.
+	switch *mode {
+	case "run":
+	case "find-max":
+		if err := checkFindMax(cfg); err != nil {
+			log.Fatalf("Invalid configuration for find-max mode: %v", err)
+		}
+	default:
+		log.Fatalf("Unknown mode: %s", *mode)
+	}
+	runDuration := time.Duration(cfg.Duration)
+	if cfg.Phases != nil {
+		runDuration = cfg.Phases.Total()
+	}
+	limited := runDuration > 0 || cfg.MaxSessions > 0 || cfg.MaxSessionsPerWorker > 0
 
 	ctx, cancel := context.WithCancel(context.Background())
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	var wg sync.WaitGroup
+	collector := stats.NewCollector()
+	if *metricsAddr != "" {
+		exporter := metrics.NewExporter()
+		collector.SetObserver(exporter)
+		ln, err := net.Listen("tcp", *metricsAddr)
+		if err != nil {
+			log.Fatalf("Failed to listen on metrics address: %v", err)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", exporter.Handler())
+		go func() {
+			if err := http.Serve(ln, mux); err != nil {
+				log.Printf("Metrics server stopped: %v", err)
+			}
+		}()
+		log.Printf("Serving Prometheus metrics on http://%s/metrics", ln.Addr())
+	}
+	shared := &worker.Shared{
+		Collector: collector,
+		Sessions:  worker.NewBudget(cfg.MaxSessions),
+	}
+	switch {
+	case cfg.RateSchedule != nil:
+		shared.Pacer = worker.NewScheduledPacer(cfg.RateSchedule, worker.PacerOptionsFrom(cfg))
+		log.Printf("Target throughput follows a %s rate schedule across all workers", cfg.RateSchedule.Type)
+	case cfg.TargetTPS > 0:
+		shared.Pacer = worker.NewPacer(cfg.TargetTPS, worker.PacerOptionsFrom(cfg))
+		log.Printf("Target throughput: %.2f TPS across all workers", cfg.TargetTPS)
+	}
+	workers := &pool{ctx: ctx, cfg: cfg, shared: shared}
+	interval := time.Duration(*reportInterval) * time.Second
+
+	exitCode := exitOK
+	var findMaxResult *stats.FindMaxResult
+	collector.Start()
+	if *mode == "find-max" {
+		exitCode, findMaxResult = findMax(workers, collector, cfg.FindMax, interval, sigChan)
+		cancel()
+		workers.wg.Wait()
+	} else {
+		// Phases start first, so that no session runs before warmup is
+		// excluded from the measurements.
+		stopPhases := func() {}
+		if cfg.Phases != nil {
+			stopPhases = startPhases(collector, cfg.Phases)
+		}
+		var timeout <-chan time.Time
+		if runDuration > 0 {
+			timeout = time.After(runDuration)
+		}
+		log.Printf("Starting workload with concurrency %d", cfg.Concurrency)
+		workers.grow(cfg.Concurrency)
+
+		if interval > 0 {
+			go collector.Report(ctx, interval, cfg.Concurrency, os.Stdout)
+		}
+
+		done := make(chan struct{})
+		go func() {
+			workers.wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-sigChan:
+			log.Println("Shutdown signal received, stopping workers...")
+			if limited {
+				exitCode = exitInterrupted
+			}
+		case <-timeout:
+			log.Printf("Run duration of %v reached, stopping workers...", runDuration)
+		case <-done:
+			log.Println("Session limit reached.")
+		}
+		stopPhases()
+		cancel()
+
+		<-done
+	}
+	log.Println("All workers have stopped.")
+	collector.SetMeasuring(false)
+
+	summary := stats.NewSummary(collector.Total(), collector.Measured(), workers.size)
+	summary.FindMax = findMaxResult
+	if err := summary.WriteMarkdown(os.Stdout); err != nil {
+		log.Printf("Failed to print summary: %v", err)
+	}
+	if *reportPath != "" {
+		if err := writeReport(*reportPath, summary); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+		log.Printf("Summary written to %s", *reportPath)
+	}
+	if *mode == "run" && exitCode == exitOK && summary.FailedSessions > 0 {
+		exitCode = exitSessionErrors
+	}
+	log.Printf("Exiting with status %d.", exitCode)
+	os.Exit(exitCode)
+}
+
+// pool starts workers on demand.
+type pool struct {
+	ctx    context.Context
+	cfg    *config.Config
+	shared *worker.Shared
+	wg     sync.WaitGroup
+	size   int
+}
 
-	for i := 0; i < cfg.Concurrency; i++ {
-		w, err := worker.New(i+1, cfg)
+// grow starts workers until there are n of them.
+func (p *pool) grow(n int) {
+	for p.size < n {
+		id := p.size + 1
+		w, err := worker.New(id, p.cfg, p.shared)
 		if err != nil {
-			log.Fatalf("Failed to create worker %d: %v", i+1, err)
+			log.Fatalf("Failed to create worker %d: %v", id, err)
 		}
-		wg.Add(1)
+		p.wg.Add(1)
 		go func() {
-			defer wg.Done()
-			w.Run(ctx)
+			defer p.wg.Done()
+			w.Run(p.ctx)
 		}()
+		p.size = id
 	}
+}
+
+// startPhases switches measuring off for the warmup and cooldown phases.
+// The returned function cancels the pending phase changes.
+func startPhases(collector *stats.Collector, phases *config.Phases) func() {
+	warmup := time.Duration(phases.Warmup)
+	measureEnd := warmup + time.Duration(phases.Measure)
 
-	<-sigChan
-	log.Println("Shutdown signal received, stopping workers...")
-	cancel()
+	var timers []*time.Timer
+	if warmup > 0 {
+		collector.SetMeasuring(false)
+		log.Printf("Warmup for %v", warmup)
+		timers = append(timers, time.AfterFunc(warmup, func() {
+			log.Printf("Warmup finished, measuring for %v", time.Duration(phases.Measure))
+			collector.SetMeasuring(true)
+		}))
+	}
+	if phases.Cooldown > 0 {
+		timers = append(timers, time.AfterFunc(measureEnd, func() {
+			log.Printf("Measurement finished, cooling down for %v", time.Duration(phases.Cooldown))
+			collector.SetMeasuring(false)
+		}))
+	}
+	return func() {
+		for _, t := range timers {
+			t.Stop()
+		}
+	}
+}
 
-	wg.Wait()
-	log.Println("All workers have stopped. Exiting.")
+// writeReport writes summary to path, choosing the format from the file
+// extension.
+func writeReport(path string, summary *stats.Summary) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		err = summary.WriteMarkdown(f)
+	default:
+		err = summary.WriteJSON(f)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
 }
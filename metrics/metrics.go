@@ -0,0 +1,180 @@
+package metrics
+
+import (
+	"database_workload/stats"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "database_workload"
+
+// noTemplate is the template label of errors that are not caused by a
+// template statement, such as connection or commit failures.
+const noTemplate = "none"
+
+// latencyBuckets covers 0.5ms to ~16s.
+var latencyBuckets = prometheus.ExponentialBuckets(0.0005, 2, 16)
+
+// Exporter exposes workload measurements as Prometheus metrics.
+// It implements stats.Observer.
+type Exporter struct {
+	registry          *prometheus.Registry
+	statements        *prometheus.CounterVec
+	statementDuration *prometheus.HistogramVec
+	statementRows     *prometheus.CounterVec
+	sessions          *prometheus.CounterVec
+	sessionDuration   *prometheus.HistogramVec
+	sessionLag        prometheus.Histogram
+	retries           *prometheus.CounterVec
+	tolerated         *prometheus.CounterVec
+	transactions      *prometheus.CounterVec
+	connections       prometheus.Counter
+	errors            *prometheus.CounterVec
+	initErrors        *prometheus.CounterVec
+}
+
+// NewExporter creates an Exporter with all metrics registered.
+func NewExporter() *Exporter {
+	e := &Exporter{
+		registry: prometheus.NewRegistry(),
+		statements: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "statements_total",
+			Help:      "Number of statements executed, by template index.",
+		}, []string{"template"}),
+		statementDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "statement_duration_seconds",
+			Help:      "Statement latency, by template index.",
+			Buckets:   latencyBuckets,
+		}, []string{"template"}),
+		statementRows: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "statement_rows_total",
+			Help:      "Number of rows returned or affected by statements, by template index.",
+		}, []string{"template"}),
+		sessions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "sessions_total",
+			Help:      "Number of sessions run, by transaction type and result (ok or error).",
+		}, []string{"transaction", "result"}),
+		sessionDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "session_duration_seconds",
+			Help:      "Session latency, by transaction type.",
+			Buckets:   latencyBuckets,
+		}, []string{"transaction"}),
+		sessionLag: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "session_start_lag_seconds",
+			Help:      "How late paced sessions started compared to their intended start time.",
+			Buckets:   latencyBuckets,
+		}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "retries_total",
+			Help:      "Number of sessions retried, by transaction type and MySQL error number.",
+		}, []string{"transaction", "code"}),
+		tolerated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tolerated_errors_total",
+			Help:      "Number of errors tolerated by templates, by template index and MySQL error number.",
+		}, []string{"template", "code"}),
+		transactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transactions_total",
+			Help:      "Number of transactions, by result (committed or rolled_back).",
+		}, []string{"result"}),
+		connections: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "connections_opened_total",
+			Help:      "Number of database connections opened.",
+		}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "errors_total",
+			Help:      "Number of errors, by template index and MySQL error number.",
+		}, []string{"template", "code"}),
+		initErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "session_init_errors_total",
+			Help:      "Number of failed session init statements, by MySQL error number.",
+		}, []string{"code"}),
+	}
+	e.registry.MustRegister(
+		e.statements,
+		e.statementDuration,
+		e.statementRows,
+		e.sessions,
+		e.sessionDuration,
+		e.sessionLag,
+		e.retries,
+		e.tolerated,
+		e.transactions,
+		e.connections,
+		e.errors,
+		e.initErrors,
+	)
+	return e
+}
+
+// Handler returns an http.Handler serving the metrics in the Prometheus
+// exposition format.
+func (e *Exporter) Handler() http.Handler {
+	return promhttp.HandlerFor(e.registry, promhttp.HandlerOpts{})
+}
+
+func (e *Exporter) ObserveStatement(template string, d time.Duration, rows int64, err error) {
+	e.statements.WithLabelValues(template).Inc()
+	e.statementDuration.WithLabelValues(template).Observe(d.Seconds())
+	if rows > 0 {
+		e.statementRows.WithLabelValues(template).Add(float64(rows))
+	}
+	if err != nil {
+		e.errors.WithLabelValues(template, stats.ErrorCode(err)).Inc()
+	}
+}
+
+func (e *Exporter) ObserveSession(transaction string, d time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	e.sessions.WithLabelValues(transaction, result).Inc()
+	e.sessionDuration.WithLabelValues(transaction).Observe(d.Seconds())
+}
+
+func (e *Exporter) ObserveTolerated(template string, err error) {
+	e.tolerated.WithLabelValues(template, stats.ErrorCode(err)).Inc()
+}
+
+func (e *Exporter) ObserveRetry(transaction string, err error) {
+	e.retries.WithLabelValues(transaction, stats.ErrorCode(err)).Inc()
+}
+
+func (e *Exporter) ObserveLag(d time.Duration) {
+	e.sessionLag.Observe(d.Seconds())
+}
+
+func (e *Exporter) ObserveCommit() {
+	e.transactions.WithLabelValues("committed").Inc()
+}
+
+func (e *Exporter) ObserveRollback() {
+	e.transactions.WithLabelValues("rolled_back").Inc()
+}
+
+func (e *Exporter) ObserveConnection() {
+	e.connections.Inc()
+}
+
+func (e *Exporter) ObserveError(err error) {
+	e.errors.WithLabelValues(noTemplate, stats.ErrorCode(err)).Inc()
+}
+
+func (e *Exporter) ObserveInitError(err error) {
+	e.initErrors.WithLabelValues(stats.ErrorCode(err)).Inc()
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestExporter(t *testing.T) {
+	e := NewExporter()
+	e.ObserveStatement("0", time.Millisecond, 3, nil)
+	e.ObserveStatement("1", time.Millisecond, 0, &mysql.MySQLError{Number: 1213})
+	e.ObserveSession("new_order", 2*time.Millisecond, nil)
+	e.ObserveRetry("new_order", &mysql.MySQLError{Number: 9007})
+	e.ObserveTolerated("2", &mysql.MySQLError{Number: 1062})
+	e.ObserveInitError(&mysql.MySQLError{Number: 1049})
+	e.ObserveCommit()
+	e.ObserveConnection()
+	e.ObserveError(&mysql.MySQLError{Number: 1045})
+
+	rec := httptest.NewRecorder()
+	e.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	out := string(body)
+
+	for _, want := range []string{
+		`database_workload_statements_total{template="0"} 1`,
+		`database_workload_errors_total{code="1213",template="1"} 1`,
+		`database_workload_errors_total{code="1045",template="none"} 1`,
+		`database_workload_sessions_total{result="ok",transaction="new_order"} 1`,
+		`database_workload_transactions_total{result="committed"} 1`,
+		`database_workload_tolerated_errors_total{code="1062",template="2"} 1`,
+		`database_workload_session_init_errors_total{code="1049"} 1`,
+		`database_workload_retries_total{code="9007",transaction="new_order"} 1`,
+		`database_workload_connections_opened_total 1`,
+		`database_workload_statement_duration_seconds_count{template="0"} 1`,
+		`database_workload_statement_rows_total{template="0"} 3`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in metrics output", want)
+		}
+	}
+}
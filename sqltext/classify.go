@@ -0,0 +1,85 @@
+package sqltext
+
+import "strings"
+
+// queryKeywords are the leading keywords of statements that return a result
+// set. Table maintenance statements such as CHECK TABLE report their outcome
+// as a result set too.
+var queryKeywords = map[string]bool{
+	"SELECT":   true,
+	"TABLE":    true,
+	"VALUES":   true,
+	"SHOW":     true,
+	"EXPLAIN":  true,
+	"DESCRIBE": true,
+	"DESC":     true,
+	"HELP":     true,
+	"CHECK":    true,
+	"CHECKSUM": true,
+	"ANALYZE":  true,
+	"OPTIMIZE": true,
+	"REPAIR":   true,
+}
+
+// IsQuery reports whether sql returns a result set, judging by its leading
+// keyword. Comments and opening parentheses before the keyword are skipped,
+// and for WITH the statement following the common table expressions
+// decides. Statements it does not recognise, such as CALL, are not queries.
+func IsQuery(sql string) bool {
+	words := keywords(sql)
+	if len(words) == 0 {
+		return false
+	}
+	if words[0] != "WITH" {
+		return queryKeywords[words[0]]
+	}
+	for _, w := range words[1:] {
+		switch w {
+		case "SELECT", "TABLE", "VALUES":
+			return true
+		case "UPDATE", "DELETE", "INSERT", "REPLACE":
+			return false
+		}
+	}
+	return false
+}
+
+// keywords returns the upper-cased words of sql that are outside quotes,
+// comments and parentheses. The first word is returned even if it is inside
+// parentheses, as in (SELECT ...) UNION (SELECT ...).
+func keywords(sql string) []string {
+	var words []string
+	depth, base := 0, -1
+	for _, tok := range lex(sql) {
+		if tok.kind != tokenText {
+			continue
+		}
+		text := tok.text
+		for i := 0; i < len(text); {
+			c := text[i]
+			switch {
+			case c == '(':
+				depth++
+				i++
+			case c == ')':
+				depth--
+				i++
+			case isIdentByte(c):
+				j := i
+				for j < len(text) && isIdentByte(text[j]) {
+					j++
+				}
+				if base < 0 {
+					base = depth
+				}
+				if depth == base {
+					words = append(words, strings.ToUpper(text[i:j]))
+				}
+				i = j
+			default:
+				i++
+			}
+		}
+	}
+	return words
+}
@@ -0,0 +1,31 @@
+package sqltext
+
+import "testing"
+
+func TestIsQuery(t *testing.T) {
+	for sql, want := range map[string]bool{
+		"SELECT 1":                                   true,
+		"  select * from t for update":               true,
+		"(SELECT 1) UNION (SELECT 2)":                true,
+		"/* comment */ SELECT 1":                     true,
+		"-- comment\nSELECT 1":                       true,
+		"# comment\nSELECT 1":                        true,
+		"SELECT /*+ MAX_EXECUTION_TIME(100) */ 1":    true,
+		"WITH cte AS (SELECT 1) SELECT * FROM cte":   true,
+		"WITH RECURSIVE c (n) AS (SELECT 1) TABLE c": true,
+		"WITH c AS (SELECT id FROM t) UPDATE t, c SET t.a = 1 WHERE t.id = c.id": false,
+		"SHOW TABLES":                   true,
+		"EXPLAIN SELECT 1":              true,
+		"DESC t":                        true,
+		"TABLE t":                       true,
+		"INSERT INTO t SELECT * FROM u": false,
+		"UPDATE t SET a = 'SELECT'":     false,
+		"/* SELECT */ DELETE FROM t":    false,
+		"CALL p()":                      false,
+		"":                              false,
+	} {
+		if got := IsQuery(sql); got != want {
+			t.Errorf("IsQuery(%q) = %t, want %t", sql, got, want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package sqltext
+
+import "strings"
+
+type tokenKind int
+
+const (
+	// tokenText is SQL text that may be sent as is.
+	tokenText tokenKind = iota
+	// tokenQuoted is a string literal or quoted identifier, whose content
+	// must not be interpreted.
+	tokenQuoted
+	// tokenComment is a comment.
+	tokenComment
+	// tokenPositional is a ? placeholder.
+	tokenPositional
+	// tokenNamed is a :name or {{name}} placeholder.
+	tokenNamed
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	name string // placeholder name of a tokenNamed
+}
+
+// lex splits MySQL text into tokens. It only recognises what matters for
+// placeholders: quoted strings and identifiers, comments and placeholders.
+// Everything else is returned as text.
+func lex(sql string) []token {
+	var tokens []token
+	textStart := 0
+	flush := func(end int) {
+		if end > textStart {
+			tokens = append(tokens, token{kind: tokenText, text: sql[textStart:end]})
+		}
+	}
+	emit := func(start, end int, kind tokenKind, name string) {
+		flush(start)
+		tokens = append(tokens, token{kind: kind, text: sql[start:end], name: name})
+		textStart = end
+	}
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := quotedEnd(sql, i)
+			emit(i, end, tokenQuoted, "")
+			i = end
+		case c == '#' || isDashComment(sql, i):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql)
+			} else {
+				end += i + 1
+			}
+			emit(i, end, tokenComment, "")
+			i = end
+		case strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				end = len(sql)
+			} else {
+				end += i + 4
+			}
+			emit(i, end, tokenComment, "")
+			i = end
+		case c == '?':
+			emit(i, i+1, tokenPositional, "")
+			i++
+		default:
+			if name, n := namedPlaceholder(sql, i); n > 0 {
+				emit(i, i+n, tokenNamed, name)
+				i += n
+				continue
+			}
+			i++
+		}
+	}
+	flush(len(sql))
+	return tokens
+}
+
+// quotedEnd returns the end of the quoted string or identifier starting at
+// sql[i]. Quotes are escaped by doubling them and, except in identifiers, by
+// a backslash. An unterminated quote extends to the end of sql.
+func quotedEnd(sql string, i int) int {
+	quote := sql[i]
+	for j := i + 1; j < len(sql); j++ {
+		switch sql[j] {
+		case '\\':
+			if quote != '`' {
+				j++
+			}
+		case quote:
+			if j+1 < len(sql) && sql[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(sql)
+}
+
+// isDashComment reports whether a -- comment starts at sql[i]. MySQL requires
+// the dashes to be followed by whitespace or the end of the text, so that
+// "1--1" stays an expression.
+func isDashComment(sql string, i int) bool {
+	if !strings.HasPrefix(sql[i:], "--") {
+		return false
+	}
+	if i+2 == len(sql) {
+		return true
+	}
+	switch sql[i+2] {
+	case ' ', '\t', '\n', '\r':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package sqltext
+
+import "testing"
+
+func TestLex_Placeholders(t *testing.T) {
+	for sql, want := range map[string]int{
+		"SELECT ?":                                  1,
+		"SELECT '?', \"?\", `?`":                    0,
+		"SELECT 'it''s ?', 'a\\'?'":                 0,
+		"SELECT doc->>'$.a?' FROM t WHERE id = ?":   1,
+		"SELECT 1 -- ?\nFROM t WHERE id = ?":        1,
+		"SELECT 1 # ?\nFROM t WHERE id = ?":         1,
+		"SELECT /* ? */ 1 FROM t WHERE id IN (?,?)": 2,
+		"SELECT 1--?":                               1,
+		"SELECT ':a' FROM t WHERE id = :a":          1,
+		"SELECT 'unterminated ?":                    0,
+	} {
+		n := 0
+		for _, tok := range lex(sql) {
+			if tok.kind == tokenPositional || tok.kind == tokenNamed {
+				n++
+			}
+		}
+		if n != want {
+			t.Errorf("%q: got %d placeholders, want %d", sql, n, want)
+		}
+	}
+}
+
+func TestLex_Text(t *testing.T) {
+	sql := "SELECT 'a' /* b */ FROM t -- c\nWHERE x = ? # d"
+	out := ""
+	for _, tok := range lex(sql) {
+		out += tok.text
+	}
+	if out != sql {
+		t.Errorf("tokens do not cover the SQL: %q", out)
+	}
+}
@@ -0,0 +1,154 @@
+// Package sqltext parses SQL templates: it finds their placeholders and
+// expands array params.
+package sqltext
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Statement is an SQL template split at its placeholders.
+type Statement struct {
+	// parts holds the SQL around the placeholders; it has one more element
+	// than args.
+	parts []string
+	// args holds, for each placeholder in order, the index of its param, so
+	// a named param used twice yields the same value twice.
+	args []int
+}
+
+// Parse splits sql at its placeholders and binds them to params. names holds
+// the name of each param ("" for an unnamed one). Placeholders are either
+// all positional (?), matched to params in order, or all named (:name or
+// {{name}}), matched to params by name. Placeholders inside string
+// literals, quoted identifiers and comments are ignored.
+func Parse(sql string, names []string) (*Statement, error) {
+	index := make(map[string]int, len(names))
+	for i, name := range names {
+		if name == "" {
+			continue
+		}
+		if _, ok := index[name]; ok {
+			return nil, fmt.Errorf("duplicate param name: %s", name)
+		}
+		index[name] = i
+	}
+
+	s := &Statement{}
+	var b strings.Builder
+	positional, named := 0, 0
+	for _, tok := range lex(sql) {
+		switch tok.kind {
+		case tokenPositional:
+			s.args = append(s.args, positional)
+			positional++
+		case tokenNamed:
+			idx, ok := index[tok.name]
+			if !ok {
+				return nil, fmt.Errorf("placeholder %s has no param of that name", tok.name)
+			}
+			s.args = append(s.args, idx)
+			named++
+		default:
+			b.WriteString(tok.text)
+			continue
+		}
+		s.parts = append(s.parts, b.String())
+		b.Reset()
+	}
+	s.parts = append(s.parts, b.String())
+
+	if positional > 0 && named > 0 {
+		return nil, fmt.Errorf("named and ? placeholders cannot be mixed")
+	}
+	if named == 0 {
+		if positional != len(names) {
+			return nil, fmt.Errorf("%d ? placeholders but %d params", positional, len(names))
+		}
+		return s, nil
+	}
+	used := make([]bool, len(names))
+	for _, idx := range s.args {
+		used[idx] = true
+	}
+	for i, u := range used {
+		if !u {
+			return nil, fmt.Errorf("param %d (%s) is not used by any placeholder", i, names[i])
+		}
+	}
+	return s, nil
+}
+
+// Expand returns the SQL and driver args for one execution, given the value
+// of each param. An array value ([]interface{}) expands to one placeholder
+// per element, for use in IN (...) lists.
+func (s *Statement) Expand(values []interface{}) (string, []interface{}) {
+	var b strings.Builder
+	args := make([]interface{}, 0, len(s.args))
+	for i, idx := range s.args {
+		b.WriteString(s.parts[i])
+		arr, ok := values[idx].([]interface{})
+		if !ok {
+			b.WriteByte('?')
+			args = append(args, values[idx])
+			continue
+		}
+		if len(arr) == 0 {
+			// An empty IN () is a syntax error: bind a single NULL instead,
+			// which matches nothing.
+			b.WriteByte('?')
+			args = append(args, nil)
+			continue
+		}
+		b.WriteString(strings.TrimSuffix(strings.Repeat("?,", len(arr)), ","))
+		args = append(args, arr...)
+	}
+	b.WriteString(s.parts[len(s.parts)-1])
+	return b.String(), args
+}
+
+// namedPlaceholder returns the name of the named placeholder at sql[i:] and
+// its length in bytes, or a zero length if there is none.
+func namedPlaceholder(sql string, i int) (string, int) {
+	if strings.HasPrefix(sql[i:], "{{") {
+		end := strings.Index(sql[i+2:], "}}")
+		if end < 0 {
+			return "", 0
+		}
+		name := strings.TrimSpace(sql[i+2 : i+2+end])
+		if !isIdent(name) {
+			return "", 0
+		}
+		return name, end + 4
+	}
+	// A colon preceded by a word character or another colon is not a
+	// placeholder, e.g. in the := operator after a variable.
+	if sql[i] != ':' || (i > 0 && (isIdentByte(sql[i-1]) || sql[i-1] == ':')) {
+		return "", 0
+	}
+	j := i + 1
+	for j < len(sql) && isIdentByte(sql[j]) {
+		j++
+	}
+	name := sql[i+1 : j]
+	if !isIdent(name) {
+		return "", 0
+	}
+	return name, j - i
+}
+
+func isIdent(s string) bool {
+	if s == "" || (s[0] >= '0' && s[0] <= '9') {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isIdentByte(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
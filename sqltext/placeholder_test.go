@@ -0,0 +1,52 @@
+package sqltext
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse_Named(t *testing.T) {
+	stmt, err := Parse("SELECT * FROM t WHERE a = :user_id AND b IN ({{ ids }}) AND c = :user_id", []string{"ids", "user_id"})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	sql, args := stmt.Expand([]interface{}{[]interface{}{1, 2}, 7})
+	if sql != "SELECT * FROM t WHERE a = ? AND b IN (?,?) AND c = ?" {
+		t.Errorf("unexpected SQL: %s", sql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{7, 1, 2, 7}) {
+		t.Errorf("unexpected args: %v", args)
+	}
+}
+
+func TestParse_Positional(t *testing.T) {
+	stmt, err := Parse("SELECT * FROM t WHERE a = ? AND b = '12:30?' AND c IN (?) AND @x := 1", []string{"", ""})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	sql, args := stmt.Expand([]interface{}{1, []interface{}{}})
+	if sql != "SELECT * FROM t WHERE a = ? AND b = '12:30?' AND c IN (?) AND @x := 1" {
+		t.Errorf("unexpected SQL: %s", sql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, nil}) {
+		t.Errorf("unexpected args: %v", args)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, tc := range []struct {
+		sql   string
+		names []string
+	}{
+		{"SELECT :a", []string{"b"}},
+		{"SELECT :a, ?", []string{"a", ""}},
+		{"SELECT :a", []string{"a", "b"}},
+		{"SELECT :a", []string{"a", "a"}},
+		{"SELECT ?", nil},
+		{"SELECT '?'", []string{""}},
+	} {
+		if _, err := Parse(tc.sql, tc.names); err == nil {
+			t.Errorf("expected an error for %q with %v", tc.sql, tc.names)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketRoundTrip(t *testing.T) {
+	for _, v := range []int64{0, 1, 127, 128, 129, 255, 256, 1000, 123456, 1 << 30} {
+		idx := bucketIndex(v)
+		upper := bucketValue(idx)
+		if upper < v {
+			t.Errorf("bucket %d for value %d has upper bound %d below the value", idx, v, upper)
+		}
+		if float64(upper-v) > float64(v)/subBucketHalf {
+			t.Errorf("bucket %d for value %d has upper bound %d, relative error too large", idx, v, upper)
+		}
+	}
+}
+
+func TestHistogramPercentiles(t *testing.T) {
+	h := NewHistogram()
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+	if h.Count() != 1000 {
+		t.Fatalf("expected 1000 values, got %d", h.Count())
+	}
+	if h.Min() != time.Millisecond {
+		t.Errorf("expected min 1ms, got %v", h.Min())
+	}
+	if h.Max() != time.Second {
+		t.Errorf("expected max 1s, got %v", h.Max())
+	}
+
+	for _, tc := range []struct {
+		p    float64
+		want time.Duration
+	}{
+		{50, 500 * time.Millisecond},
+		{95, 950 * time.Millisecond},
+		{99, 990 * time.Millisecond},
+	} {
+		got := h.Percentile(tc.p)
+		diff := float64(got-tc.want) / float64(tc.want)
+		if diff < 0 || diff > 0.02 {
+			t.Errorf("p%v: expected about %v, got %v", tc.p, tc.want, got)
+		}
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	a, b := NewHistogram(), NewHistogram()
+	a.Record(2 * time.Millisecond)
+	b.Record(time.Millisecond)
+	b.Record(time.Minute)
+
+	a.Merge(b)
+	if a.Count() != 3 {
+		t.Errorf("expected 3 values, got %d", a.Count())
+	}
+	if a.Min() != time.Millisecond || a.Max() != time.Minute {
+		t.Errorf("unexpected min/max after merge: %v/%v", a.Min(), a.Max())
+	}
+	if a.Percentile(50) > 2*time.Millisecond+50*time.Microsecond {
+		t.Errorf("unexpected p50 after merge: %v", a.Percentile(50))
+	}
+}
+
+func TestHistogramEmpty(t *testing.T) {
+	h := NewHistogram()
+	if h.Percentile(99) != 0 || h.Mean() != 0 {
+		t.Errorf("expected zero values for an empty histogram")
+	}
+}
@@ -0,0 +1,437 @@
+package stats
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// OpStats holds the measurements of a single SQL template or transaction
+// type.
+type OpStats struct {
+	Count   uint64
+	Errors  uint64
+	Rows    uint64 // rows returned or affected; templates only
+	Retries uint64 // transaction types only
+	// Tolerated counts errors that the template tolerates; such statements
+	// count as successful.
+	Tolerated uint64
+	Latency   *Histogram
+}
+
+func newOpStats() *OpStats {
+	return &OpStats{Latency: NewHistogram()}
+}
+
+// Snapshot holds the measurements accumulated over a period of time.
+type Snapshot struct {
+	Sessions       uint64
+	SessionErrors  uint64
+	Retries        uint64
+	Tolerated      uint64
+	InitErrors     uint64 // failed session init statements; not in Errors
+	Commits        uint64
+	Rollbacks      uint64
+	Connections    uint64
+	Statements     uint64
+	Rows           uint64
+	SessionLatency *Histogram
+	Lag            *Histogram // how late paced sessions started, i.e. the backlog
+	Templates      map[string]*OpStats
+	Transactions   map[string]*OpStats
+	Errors         map[string]uint64 // keyed by ErrorCode
+}
+
+// NewSnapshot creates an empty Snapshot.
+func NewSnapshot() *Snapshot {
+	return &Snapshot{
+		SessionLatency: NewHistogram(),
+		Lag:            NewHistogram(),
+		Templates:      make(map[string]*OpStats),
+		Transactions:   make(map[string]*OpStats),
+		Errors:         make(map[string]uint64),
+	}
+}
+
+// Merge adds all measurements in o to s.
+func (s *Snapshot) Merge(o *Snapshot) {
+	s.Sessions += o.Sessions
+	s.SessionErrors += o.SessionErrors
+	s.Retries += o.Retries
+	s.Tolerated += o.Tolerated
+	s.InitErrors += o.InitErrors
+	s.Commits += o.Commits
+	s.Rollbacks += o.Rollbacks
+	s.Connections += o.Connections
+	s.Statements += o.Statements
+	s.Rows += o.Rows
+	s.SessionLatency.Merge(o.SessionLatency)
+	s.Lag.Merge(o.Lag)
+	mergeOps(s.Templates, o.Templates)
+	mergeOps(s.Transactions, o.Transactions)
+	for code, n := range o.Errors {
+		s.Errors[code] += n
+	}
+}
+
+func mergeOps(dst, src map[string]*OpStats) {
+	for name, so := range src {
+		d, ok := dst[name]
+		if !ok {
+			d = newOpStats()
+			dst[name] = d
+		}
+		d.Count += so.Count
+		d.Errors += so.Errors
+		d.Rows += so.Rows
+		d.Retries += so.Retries
+		d.Tolerated += so.Tolerated
+		d.Latency.Merge(so.Latency)
+	}
+}
+
+// StatementLatency returns the latency histogram of all templates combined.
+func (s *Snapshot) StatementLatency() *Histogram {
+	h := NewHistogram()
+	for _, t := range s.Templates {
+		h.Merge(t.Latency)
+	}
+	return h
+}
+
+// TotalErrors returns the number of errors of any kind.
+func (s *Snapshot) TotalErrors() uint64 {
+	var n uint64
+	for _, c := range s.Errors {
+		n += c
+	}
+	return n
+}
+
+// Observer receives every measurement as soon as it is recorded, e.g. to
+// export it to a monitoring system. Implementations must be safe for
+// concurrent use.
+type Observer interface {
+	ObserveStatement(template string, d time.Duration, rows int64, err error)
+	ObserveSession(transaction string, d time.Duration, err error)
+	ObserveTolerated(template string, err error)
+	ObserveRetry(transaction string, err error)
+	ObserveLag(d time.Duration)
+	ObserveCommit()
+	ObserveRollback()
+	ObserveConnection()
+	ObserveError(err error)
+	ObserveInitError(err error)
+}
+
+// Recorder accumulates measurements for a single worker.
+// It is safe for concurrent use, but is meant to be owned by one goroutine
+// so that the lock is only contended when the Collector drains it.
+type Recorder struct {
+	mu       sync.Mutex
+	snap     *Snapshot
+	observer Observer
+}
+
+func newRecorder(observer Observer) *Recorder {
+	return &Recorder{snap: NewSnapshot(), observer: observer}
+}
+
+// RecordStatement records the execution of one statement of a template.
+// rows is the number of rows the statement returned or affected.
+func (r *Recorder) RecordStatement(template string, d time.Duration, rows int64, err error) {
+	if r.observer != nil {
+		r.observer.ObserveStatement(template, d, rows, err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.snap.Templates[template]
+	if !ok {
+		t = newOpStats()
+		r.snap.Templates[template] = t
+	}
+	t.Count++
+	t.Latency.Record(d)
+	r.snap.Statements++
+	if rows > 0 {
+		t.Rows += uint64(rows)
+		r.snap.Rows += uint64(rows)
+	}
+	if err != nil {
+		t.Errors++
+		r.snap.Errors[ErrorCode(err)]++
+	}
+}
+
+// RecordSession records the completion of one session of a transaction
+// type; the empty name stands for the top-level templates. A non-nil err
+// means the session was aborted; its cause is expected to have been recorded
+// by RecordStatement or RecordError already.
+func (r *Recorder) RecordSession(transaction string, d time.Duration, err error) {
+	if r.observer != nil {
+		r.observer.ObserveSession(transaction, d, err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.snap.Sessions++
+	r.snap.SessionLatency.Record(d)
+	if err != nil {
+		r.snap.SessionErrors++
+	}
+	if transaction == "" {
+		return
+	}
+	t, ok := r.snap.Transactions[transaction]
+	if !ok {
+		t = newOpStats()
+		r.snap.Transactions[transaction] = t
+	}
+	t.Count++
+	t.Latency.Record(d)
+	if err != nil {
+		t.Errors++
+	}
+}
+
+// RecordTolerated records an error that a template tolerates. The statement
+// itself is recorded as successful by RecordStatement.
+func (r *Recorder) RecordTolerated(template string, err error) {
+	if r.observer != nil {
+		r.observer.ObserveTolerated(template, err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.snap.Tolerated++
+	t, ok := r.snap.Templates[template]
+	if !ok {
+		t = newOpStats()
+		r.snap.Templates[template] = t
+	}
+	t.Tolerated++
+}
+
+// RecordRetry records that a session of a transaction type is retried after
+// err. The attempt that failed is not counted as a session.
+func (r *Recorder) RecordRetry(transaction string, err error) {
+	if r.observer != nil {
+		r.observer.ObserveRetry(transaction, err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.snap.Retries++
+	if transaction == "" {
+		return
+	}
+	t, ok := r.snap.Transactions[transaction]
+	if !ok {
+		t = newOpStats()
+		r.snap.Transactions[transaction] = t
+	}
+	t.Retries++
+}
+
+// RecordLag records how late a paced session started compared to its
+// intended start time.
+func (r *Recorder) RecordLag(d time.Duration) {
+	if r.observer != nil {
+		r.observer.ObserveLag(d)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.snap.Lag.Record(d)
+}
+
+// RecordCommit records a committed transaction.
+func (r *Recorder) RecordCommit() {
+	if r.observer != nil {
+		r.observer.ObserveCommit()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.snap.Commits++
+}
+
+// RecordRollback records a rolled back transaction.
+func (r *Recorder) RecordRollback() {
+	if r.observer != nil {
+		r.observer.ObserveRollback()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.snap.Rollbacks++
+}
+
+// RecordConnection records that a new database connection was opened.
+func (r *Recorder) RecordConnection() {
+	if r.observer != nil {
+		r.observer.ObserveConnection()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.snap.Connections++
+}
+
+// RecordError records an error that did not come from a template statement,
+// such as a failure to connect or to commit.
+func (r *Recorder) RecordError(err error) {
+	if r.observer != nil {
+		r.observer.ObserveError(err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.snap.Errors[ErrorCode(err)]++
+}
+
+// RecordInitError records the failure of a session init statement on a new
+// connection. It is not counted as a workload error.
+func (r *Recorder) RecordInitError(err error) {
+	if r.observer != nil {
+		r.observer.ObserveInitError(err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.snap.InitErrors++
+}
+
+// drain returns the measurements recorded so far and resets the recorder.
+func (r *Recorder) drain() *Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.snap
+	r.snap = NewSnapshot()
+	return s
+}
+
+// Collector aggregates the measurements of all registered Recorders.
+// Every measurement is reported by Collect, but only those made while
+// measuring is on are added to the running total.
+type Collector struct {
+	mu        sync.Mutex
+	recorders []*Recorder
+	interval  *Snapshot
+	total     *Snapshot
+	observer  Observer
+
+	measuring     bool
+	measuredSince time.Time
+	measured      time.Duration
+}
+
+// NewCollector creates an empty Collector that is measuring.
+func NewCollector() *Collector {
+	return &Collector{
+		interval:      NewSnapshot(),
+		total:         NewSnapshot(),
+		measuring:     true,
+		measuredSince: time.Now(),
+	}
+}
+
+// SetObserver makes every Recorder created afterwards forward its
+// measurements to o.
+func (c *Collector) SetObserver(o Observer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.observer = o
+}
+
+// NewRecorder creates a Recorder whose measurements are gathered by c.
+func (c *Collector) NewRecorder() *Recorder {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r := newRecorder(c.observer)
+	c.recorders = append(c.recorders, r)
+	return r
+}
+
+// SetMeasuring turns the running total on or off, e.g. to exclude warmup
+// and cooldown from the summary. Measurements made before the call are
+// attributed to the previous state.
+func (c *Collector) SetMeasuring(on bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.drainLocked()
+	if on == c.measuring {
+		return
+	}
+	now := time.Now()
+	if c.measuring {
+		c.measured += now.Sub(c.measuredSince)
+	}
+	c.measuring = on
+	c.measuredSince = now
+}
+
+// Start marks the start of the load, so that the setup time since
+// NewCollector is not counted as measured.
+func (c *Collector) Start() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.measured = 0
+	c.measuredSince = time.Now()
+}
+
+// Measured returns how long the Collector has been measuring in total.
+func (c *Collector) Measured() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.measuring {
+		return c.measured + time.Since(c.measuredSince)
+	}
+	return c.measured
+}
+
+func (c *Collector) drainLocked() {
+	for _, r := range c.recorders {
+		s := r.drain()
+		c.interval.Merge(s)
+		if c.measuring {
+			c.total.Merge(s)
+		}
+	}
+}
+
+// Collect drains all recorders and returns the measurements made since the
+// previous call, whether or not they were measured.
+func (c *Collector) Collect() *Snapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.drainLocked()
+	s := c.interval
+	c.interval = NewSnapshot()
+	return s
+}
+
+// Total drains all recorders and returns every measurement made while
+// measuring was on.
+func (c *Collector) Total() *Snapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.drainLocked()
+	s := NewSnapshot()
+	s.Merge(c.total)
+	return s
+}
+
+// ErrorCode returns a short label classifying err, used to break errors down
+// in reports. MySQL server errors are labelled by their error number.
+func ErrorCode(err error) string {
+	var myErr *mysql.MySQLError
+	switch {
+	case errors.As(err, &myErr):
+		return strconv.Itoa(int(myErr.Number))
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, mysql.ErrInvalidConn):
+		return "bad_conn"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,151 @@
+package stats
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestCollectorCollect(t *testing.T) {
+	c := NewCollector()
+	r1, r2 := c.NewRecorder(), c.NewRecorder()
+
+	r1.RecordStatement("0", time.Millisecond, 5, nil)
+	r1.RecordSession("", 2*time.Millisecond, nil)
+	r2.RecordStatement("0", time.Millisecond, 0, &mysql.MySQLError{Number: 1213})
+	r2.RecordSession("", time.Millisecond, errors.New("aborted"))
+
+	snap := c.Collect()
+	if snap.Sessions != 2 || snap.SessionErrors != 1 {
+		t.Errorf("unexpected session counts: %d sessions, %d errors", snap.Sessions, snap.SessionErrors)
+	}
+	tmpl := snap.Templates["0"]
+	if tmpl == nil || tmpl.Count != 2 || tmpl.Errors != 1 || tmpl.Rows != 5 {
+		t.Fatalf("unexpected template stats: %+v", tmpl)
+	}
+	if snap.Errors["1213"] != 1 {
+		t.Errorf("expected one 1213 error, got %v", snap.Errors)
+	}
+
+	r1.RecordRetry("new_order", &mysql.MySQLError{Number: 9007})
+	if snap := c.Collect(); snap.Retries != 1 || snap.Transactions["new_order"].Retries != 1 || snap.Sessions != 0 {
+		t.Errorf("expected a retry that is not a session, got %d retries, %d sessions", snap.Retries, snap.Sessions)
+	}
+
+	if again := c.Collect(); again.Sessions != 0 || len(again.Templates) != 0 {
+		t.Errorf("expected Collect to reset recorders, got %d sessions", again.Sessions)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	if got := ErrorCode(&mysql.MySQLError{Number: 9007}); got != "9007" {
+		t.Errorf("expected 9007, got %s", got)
+	}
+	if got := ErrorCode(errors.New("boom")); got != "other" {
+		t.Errorf("expected other, got %s", got)
+	}
+}
+
+func TestWriteInterval(t *testing.T) {
+	c := NewCollector()
+	r := c.NewRecorder()
+	for i := 0; i < 10; i++ {
+		r.RecordStatement("1", time.Millisecond, 1, nil)
+		r.RecordStatement("0", time.Millisecond, 1, nil)
+		r.RecordSession("", 2*time.Millisecond, nil)
+	}
+
+	var buf bytes.Buffer
+	WriteInterval(&buf, c.Collect(), 10*time.Second, 10*time.Second, 4)
+	out := buf.String()
+	if !strings.HasPrefix(out, "[ 10s ] thds: 4 tps: 1.00 qps: 2.00 err/s: 0.00") {
+		t.Errorf("unexpected interval line: %q", out)
+	}
+	if strings.Index(out, "template 0") > strings.Index(out, "template 1") {
+		t.Errorf("expected templates in index order: %q", out)
+	}
+}
+
+func TestSortedKeys(t *testing.T) {
+	m := map[string]int{"b.0": 0, "a.10": 0, "a.2": 0, "ab.1": 0, "a.0": 0, "10": 0, "9": 0, "payment": 0}
+	want := []string{"9", "10", "a.0", "a.2", "a.10", "ab.1", "b.0", "payment"}
+	if got := sortedKeys(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestCollectorTotal(t *testing.T) {
+	c := NewCollector()
+	r := c.NewRecorder()
+
+	r.RecordSession("", time.Millisecond, nil)
+	r.RecordCommit()
+	c.Collect()
+	r.RecordSession("", time.Millisecond, errors.New("aborted"))
+	r.RecordRollback()
+
+	total := c.Total()
+	if total.Sessions != 2 || total.Commits != 1 || total.Rollbacks != 1 {
+		t.Errorf("unexpected totals: %d sessions, %d commits, %d rollbacks", total.Sessions, total.Commits, total.Rollbacks)
+	}
+}
+
+func TestCollectorMeasuring(t *testing.T) {
+	c := NewCollector()
+	r := c.NewRecorder()
+
+	c.SetMeasuring(false)
+	r.RecordSession("", time.Millisecond, nil)
+	c.SetMeasuring(true)
+	r.RecordSession("", time.Millisecond, nil)
+	r.RecordSession("", time.Millisecond, nil)
+	c.SetMeasuring(false)
+	r.RecordSession("", time.Millisecond, nil)
+
+	if interval := c.Collect(); interval.Sessions != 4 {
+		t.Errorf("expected all 4 sessions in the interval, got %d", interval.Sessions)
+	}
+	if total := c.Total(); total.Sessions != 2 {
+		t.Errorf("expected only the 2 measured sessions in the total, got %d", total.Sessions)
+	}
+
+	measured := c.Measured()
+	time.Sleep(10 * time.Millisecond)
+	if c.Measured() != measured {
+		t.Errorf("expected measured duration to stop growing while not measuring")
+	}
+}
+
+func TestCollectorStart(t *testing.T) {
+	c := NewCollector()
+	time.Sleep(20 * time.Millisecond)
+	c.Start()
+	if measured := c.Measured(); measured >= 20*time.Millisecond {
+		t.Errorf("expected the time before Start not to be measured, got %v", measured)
+	}
+}
+
+func TestRecorderTransactions(t *testing.T) {
+	c := NewCollector()
+	r := c.NewRecorder()
+	r.RecordSession("new_order", time.Millisecond, nil)
+	r.RecordSession("new_order", time.Millisecond, errors.New("aborted"))
+	r.RecordSession("payment", time.Millisecond, nil)
+	r.RecordSession("", time.Millisecond, nil)
+
+	snap := c.Collect()
+	if snap.Sessions != 4 {
+		t.Errorf("expected 4 sessions, got %d", snap.Sessions)
+	}
+	if len(snap.Transactions) != 2 {
+		t.Fatalf("expected 2 named transaction types, got %v", snap.Transactions)
+	}
+	if tx := snap.Transactions["new_order"]; tx.Count != 2 || tx.Errors != 1 {
+		t.Errorf("unexpected new_order stats: %+v", tx)
+	}
+}
@@ -0,0 +1,119 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Report prints the measurements gathered by c every interval until ctx is
+// cancelled, in the spirit of sysbench's --report-interval.
+func (c *Collector) Report(ctx context.Context, interval time.Duration, threads int, out io.Writer) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	last := start
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			snap := c.Collect()
+			WriteInterval(out, snap, now.Sub(start), now.Sub(last), threads)
+			last = now
+		}
+	}
+}
+
+// WriteInterval writes a one-line summary of snap followed by one line per
+// transaction type and per template. elapsed is the time since the start of
+// the run and period is the length of the interval covered by snap.
+func WriteInterval(out io.Writer, snap *Snapshot, elapsed, period time.Duration, threads int) {
+	secs := period.Seconds()
+	if secs <= 0 {
+		return
+	}
+	lat := snap.SessionLatency
+	fmt.Fprintf(out, "[ %ds ] thds: %d tps: %.2f qps: %.2f err/s: %.2f lat (ms) p50: %s p95: %s p99: %s max: %s",
+		int(elapsed.Round(time.Second).Seconds()), threads,
+		float64(snap.Sessions)/secs,
+		float64(snap.Statements)/secs,
+		float64(snap.TotalErrors())/secs,
+		millis(lat.Percentile(50)), millis(lat.Percentile(95)), millis(lat.Percentile(99)), millis(lat.Max()))
+	if snap.Retries > 0 {
+		fmt.Fprintf(out, " retry/s: %.2f", float64(snap.Retries)/secs)
+	}
+	if snap.InitErrors > 0 {
+		fmt.Fprintf(out, " init err/s: %.2f", float64(snap.InitErrors)/secs)
+	}
+	if snap.Tolerated > 0 {
+		fmt.Fprintf(out, " tolerated/s: %.2f", float64(snap.Tolerated)/secs)
+	}
+	if snap.Lag.Count() > 0 {
+		fmt.Fprintf(out, " backlog (ms) p99: %s max: %s", millis(snap.Lag.Percentile(99)), millis(snap.Lag.Max()))
+	}
+	fmt.Fprintln(out)
+
+	for _, name := range sortedKeys(snap.Transactions) {
+		t := snap.Transactions[name]
+		fmt.Fprintf(out, "    transaction %s: tps: %.2f err/s: %.2f lat (ms) p50: %s p95: %s p99: %s max: %s",
+			name,
+			float64(t.Count)/secs,
+			float64(t.Errors)/secs,
+			millis(t.Latency.Percentile(50)), millis(t.Latency.Percentile(95)), millis(t.Latency.Percentile(99)), millis(t.Latency.Max()))
+		if t.Retries > 0 {
+			fmt.Fprintf(out, " retry/s: %.2f", float64(t.Retries)/secs)
+		}
+		fmt.Fprintln(out)
+	}
+	for _, name := range sortedKeys(snap.Templates) {
+		t := snap.Templates[name]
+		fmt.Fprintf(out, "    template %s: qps: %.2f err/s: %.2f lat (ms) p50: %s p95: %s p99: %s max: %s\n",
+			name,
+			float64(t.Count)/secs,
+			float64(t.Errors)/secs,
+			millis(t.Latency.Percentile(50)), millis(t.Latency.Percentile(95)), millis(t.Latency.Percentile(99)), millis(t.Latency.Max()))
+	}
+}
+
+func millis(d time.Duration) string {
+	return fmt.Sprintf("%.2f", float64(d)/float64(time.Millisecond))
+}
+
+// sortedKeys returns the keys of m ordered lexically, except that a numeric
+// index, alone or after the last dot as in "<tx>.<idx>", sorts numerically
+// among the keys sharing the same prefix.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		pi, ni, oki := splitIndex(keys[i])
+		pj, nj, okj := splitIndex(keys[j])
+		if pi != pj {
+			return pi < pj
+		}
+		if oki && okj && ni != nj {
+			return ni < nj
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// splitIndex splits k into the prefix up to its last dot and the numeric
+// index after it. ok is false if k does not end with an index.
+func splitIndex(k string) (prefix string, idx int, ok bool) {
+	dot := strings.LastIndexByte(k, '.')
+	idx, err := strconv.Atoi(k[dot+1:])
+	if err != nil {
+		return k, 0, false
+	}
+	return k[:dot+1], idx, true
+}
@@ -0,0 +1,213 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// LatencySummary holds the usual latency figures of a histogram, in
+// milliseconds.
+type LatencySummary struct {
+	Min  float64 `json:"min_ms"`
+	Mean float64 `json:"mean_ms"`
+	P50  float64 `json:"p50_ms"`
+	P95  float64 `json:"p95_ms"`
+	P99  float64 `json:"p99_ms"`
+	Max  float64 `json:"max_ms"`
+}
+
+func summarizeLatency(h *Histogram) LatencySummary {
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	return LatencySummary{
+		Min:  ms(h.Min()),
+		Mean: ms(h.Mean()),
+		P50:  ms(h.Percentile(50)),
+		P95:  ms(h.Percentile(95)),
+		P99:  ms(h.Percentile(99)),
+		Max:  ms(h.Max()),
+	}
+}
+
+// OpSummary is the end-of-run summary of a single template or transaction
+// type.
+type OpSummary struct {
+	Name      string         `json:"name"`
+	Count     uint64         `json:"count"`
+	Errors    uint64         `json:"errors"`
+	Rows      uint64         `json:"rows,omitempty"`
+	Retries   uint64         `json:"retries,omitempty"`
+	Tolerated uint64         `json:"tolerated_errors,omitempty"`
+	Latency   LatencySummary `json:"latency"`
+}
+
+// Summary is the end-of-run report aggregated across all workers.
+type Summary struct {
+	Duration         float64           `json:"duration_seconds"`
+	Workers          int               `json:"workers"`
+	Sessions         uint64            `json:"sessions"`
+	FailedSessions   uint64            `json:"failed_sessions"`
+	Retries          uint64            `json:"retries"`
+	Tolerated        uint64            `json:"tolerated_errors"`
+	InitErrors       uint64            `json:"session_init_errors"`
+	Committed        uint64            `json:"committed"`
+	RolledBack       uint64            `json:"rolled_back"`
+	Connections      uint64            `json:"connections"`
+	Statements       uint64            `json:"statements"`
+	Rows             uint64            `json:"rows"`
+	TPS              float64           `json:"tps"`
+	QPS              float64           `json:"qps"`
+	Errors           map[string]uint64 `json:"errors"`
+	SessionLatency   LatencySummary    `json:"session_latency"`
+	StatementLatency LatencySummary    `json:"statement_latency"`
+	ScheduleLag      *LatencySummary   `json:"schedule_lag,omitempty"`
+	Transactions     []OpSummary       `json:"transactions,omitempty"`
+	Templates        []OpSummary       `json:"templates"`
+	FindMax          *FindMaxResult    `json:"find_max,omitempty"`
+}
+
+// StepResult is the outcome of one step of a find-max search.
+type StepResult struct {
+	Level     float64 `json:"level"`
+	TPS       float64 `json:"tps"`
+	P99       float64 `json:"p99_ms"`
+	ErrorRate float64 `json:"error_rate"`
+	Passed    bool    `json:"passed"`
+}
+
+// FindMaxResult is the outcome of a find-max search.
+type FindMaxResult struct {
+	Target string       `json:"target"`
+	Best   float64      `json:"best"` // highest passing level, zero if none passed
+	Steps  []StepResult `json:"steps"`
+}
+
+// NewSummary builds a Summary from the measurements of a whole run.
+func NewSummary(snap *Snapshot, elapsed time.Duration, workers int) *Summary {
+	s := &Summary{
+		Duration:         elapsed.Seconds(),
+		Workers:          workers,
+		Sessions:         snap.Sessions,
+		FailedSessions:   snap.SessionErrors,
+		Retries:          snap.Retries,
+		Tolerated:        snap.Tolerated,
+		InitErrors:       snap.InitErrors,
+		Committed:        snap.Commits,
+		RolledBack:       snap.Rollbacks,
+		Connections:      snap.Connections,
+		Statements:       snap.Statements,
+		Rows:             snap.Rows,
+		Errors:           snap.Errors,
+		SessionLatency:   summarizeLatency(snap.SessionLatency),
+		StatementLatency: summarizeLatency(snap.StatementLatency()),
+	}
+	if snap.Lag.Count() > 0 {
+		lag := summarizeLatency(snap.Lag)
+		s.ScheduleLag = &lag
+	}
+	if secs := elapsed.Seconds(); secs > 0 {
+		s.TPS = float64(snap.Sessions) / secs
+		s.QPS = float64(snap.Statements) / secs
+	}
+	s.Transactions = summarizeOps(snap.Transactions)
+	s.Templates = summarizeOps(snap.Templates)
+	return s
+}
+
+func summarizeOps(ops map[string]*OpStats) []OpSummary {
+	var out []OpSummary
+	for _, name := range sortedKeys(ops) {
+		o := ops[name]
+		out = append(out, OpSummary{
+			Name:      name,
+			Count:     o.Count,
+			Errors:    o.Errors,
+			Rows:      o.Rows,
+			Retries:   o.Retries,
+			Tolerated: o.Tolerated,
+			Latency:   summarizeLatency(o.Latency),
+		})
+	}
+	return out
+}
+
+// WriteJSON writes s as indented JSON.
+func (s *Summary) WriteJSON(out io.Writer) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// WriteMarkdown writes s as Markdown tables, suitable for pasting into
+// tickets.
+func (s *Summary) WriteMarkdown(out io.Writer) error {
+	w := &errWriter{w: out}
+	w.printf("## Workload summary\n\n")
+	w.printf("| Metric | Value |\n|---|---|\n")
+	w.printf("| Duration | %.1fs |\n", s.Duration)
+	w.printf("| Workers | %d |\n", s.Workers)
+	w.printf("| Sessions | %d |\n", s.Sessions)
+	w.printf("| Failed sessions | %d |\n", s.FailedSessions)
+	w.printf("| Retries | %d |\n", s.Retries)
+	w.printf("| Tolerated errors | %d |\n", s.Tolerated)
+	w.printf("| Session init errors | %d |\n", s.InitErrors)
+	w.printf("| Committed | %d |\n", s.Committed)
+	w.printf("| Rolled back | %d |\n", s.RolledBack)
+	w.printf("| Connections opened | %d |\n", s.Connections)
+	w.printf("| Statements | %d |\n", s.Statements)
+	w.printf("| Rows returned or affected | %d |\n", s.Rows)
+	w.printf("| TPS | %.2f |\n", s.TPS)
+	w.printf("| QPS | %.2f |\n", s.QPS)
+
+	w.printf("\n### Latency (ms)\n\n")
+	w.printf("| Scope | Count | Errors | Min | Mean | P50 | P95 | P99 | Max |\n")
+	w.printf("|---|---|---|---|---|---|---|---|---|\n")
+	w.latencyRow("session", s.Sessions, s.FailedSessions, s.SessionLatency)
+	if s.ScheduleLag != nil {
+		w.latencyRow("schedule lag", s.Sessions, 0, *s.ScheduleLag)
+	}
+	for _, t := range s.Transactions {
+		w.latencyRow("transaction "+t.Name, t.Count, t.Errors, t.Latency)
+	}
+	for _, t := range s.Templates {
+		w.latencyRow("template "+t.Name, t.Count, t.Errors, t.Latency)
+	}
+
+	if s.FindMax != nil {
+		w.printf("\n### Find max (%s)\n\n", s.FindMax.Target)
+		w.printf("| Level | TPS | P99 (ms) | Error rate | Passed |\n|---|---|---|---|---|\n")
+		for _, step := range s.FindMax.Steps {
+			w.printf("| %g | %.2f | %.2f | %.4f | %t |\n", step.Level, step.TPS, step.P99, step.ErrorRate, step.Passed)
+		}
+		w.printf("\nHighest sustainable %s: %g\n", s.FindMax.Target, s.FindMax.Best)
+	}
+
+	if len(s.Errors) > 0 {
+		w.printf("\n### Errors\n\n")
+		w.printf("| Code | Count |\n|---|---|\n")
+		for _, code := range sortedKeys(s.Errors) {
+			w.printf("| %s | %d |\n", code, s.Errors[code])
+		}
+	}
+	return w.err
+}
+
+func (w *errWriter) latencyRow(scope string, count, errors uint64, l LatencySummary) {
+	w.printf("| %s | %d | %d | %.2f | %.2f | %.2f | %.2f | %.2f | %.2f |\n",
+		scope, count, errors, l.Min, l.Mean, l.P50, l.P95, l.P99, l.Max)
+}
+
+// errWriter remembers the first write error so that a sequence of writes
+// can be checked once.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (w *errWriter) printf(format string, args ...interface{}) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = fmt.Fprintf(w.w, format, args...)
+}
@@ -0,0 +1,65 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func testSnapshot() *Snapshot {
+	c := NewCollector()
+	r := c.NewRecorder()
+	for i := 0; i < 100; i++ {
+		r.RecordStatement("0", time.Millisecond, 1, nil)
+		r.RecordSession("", 2*time.Millisecond, nil)
+		r.RecordCommit()
+	}
+	r.RecordStatement("0", time.Millisecond, 0, &mysql.MySQLError{Number: 1062})
+	r.RecordSession("", time.Millisecond, &mysql.MySQLError{Number: 1062})
+	r.RecordRollback()
+	return c.Total()
+}
+
+func TestSummaryJSON(t *testing.T) {
+	s := NewSummary(testSnapshot(), 10*time.Second, 4)
+
+	var buf bytes.Buffer
+	if err := s.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	var decoded Summary
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if decoded.Sessions != 101 || decoded.Committed != 100 || decoded.RolledBack != 1 {
+		t.Errorf("unexpected counts: %+v", decoded)
+	}
+	if decoded.TPS != 10.1 {
+		t.Errorf("expected tps 10.1, got %v", decoded.TPS)
+	}
+	if decoded.Errors["1062"] != 1 {
+		t.Errorf("expected one 1062 error, got %v", decoded.Errors)
+	}
+	if len(decoded.Templates) != 1 || decoded.Templates[0].Count != 101 || decoded.Templates[0].Rows != 100 {
+		t.Errorf("unexpected templates: %+v", decoded.Templates)
+	}
+}
+
+func TestSummaryMarkdown(t *testing.T) {
+	s := NewSummary(testSnapshot(), 10*time.Second, 4)
+
+	var buf bytes.Buffer
+	if err := s.WriteMarkdown(&buf); err != nil {
+		t.Fatalf("WriteMarkdown failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"| Committed | 100 |", "| template 0 | 101 | 1 |", "| 1062 | 1 |"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in markdown output:\n%s", want, out)
+		}
+	}
+}
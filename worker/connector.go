@@ -0,0 +1,71 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"database_workload/stats"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// countingConnector wraps a driver.Connector, records every connection it
+// opens and runs the session init statements on it.
+type countingConnector struct {
+	driver.Connector
+	rec  *stats.Recorder
+	init []string
+}
+
+func (c *countingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.rec.RecordConnection()
+	if len(c.init) == 0 {
+		return conn, nil
+	}
+	execer, ok := conn.(driver.ExecerContext)
+	if !ok {
+		conn.Close()
+		return nil, &initError{err: fmt.Errorf("driver connection cannot execute statements")}
+	}
+	for _, stmt := range c.init {
+		if _, err := execer.ExecContext(ctx, stmt, nil); err != nil {
+			conn.Close()
+			return nil, &initError{stmt: stmt, err: err}
+		}
+	}
+	return conn, nil
+}
+
+// initError is the failure of a session init statement. It is kept apart
+// from workload errors, since it says nothing about the workload.
+type initError struct {
+	stmt string
+	err  error
+}
+
+func (e *initError) Error() string {
+	return fmt.Sprintf("session init %q: %v", e.stmt, e.err)
+}
+
+func (e *initError) Unwrap() error {
+	return e.err
+}
+
+// openDB opens a MySQL database handle whose new connections are recorded
+// by rec and run the init statements before use.
+func openDB(dsn string, rec *stats.Recorder, init []string) (*sql.DB, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(&countingConnector{Connector: connector, rec: rec, init: init}), nil
+}
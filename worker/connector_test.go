@@ -0,0 +1,154 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"database_workload/stats"
+	"errors"
+	"io"
+	"testing"
+)
+
+// fakeConn is a driver connection that records the statements it runs,
+// including BEGIN, COMMIT and ROLLBACK. It fails on "bad" and with the
+// errors scripted for a statement, and returns the rows scripted for a query.
+type fakeConn struct {
+	driver.Conn
+	executed []string
+	args     [][]driver.NamedValue
+	closed   bool
+	errs     map[string][]error // errors of the next runs of a statement; nil succeeds
+	results  map[string]*fakeResult
+}
+
+func (c *fakeConn) run(query string, args []driver.NamedValue) error {
+	if query == "bad" {
+		return errors.New("syntax error")
+	}
+	c.executed = append(c.executed, query)
+	c.args = append(c.args, args)
+	if errs := c.errs[query]; len(errs) > 0 {
+		c.errs[query] = errs[1:]
+		return errs[0]
+	}
+	return nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.run(query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.run(query, args); err != nil {
+		return nil, err
+	}
+	result := c.results[query]
+	if result == nil {
+		result = &fakeResult{}
+	}
+	return &fakeRows{result: result}, nil
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := c.run("BEGIN", nil); err != nil {
+		return nil, err
+	}
+	return fakeTx{c}, nil
+}
+
+func (c *fakeConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+type fakeTx struct {
+	conn *fakeConn
+}
+
+func (t fakeTx) Commit() error {
+	return t.conn.run("COMMIT", nil)
+}
+
+func (t fakeTx) Rollback() error {
+	return t.conn.run("ROLLBACK", nil)
+}
+
+// fakeResult is the result set of a query.
+type fakeResult struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+type fakeRows struct {
+	result *fakeResult
+	next   int
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.result.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.result.rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.rows[r.next])
+	r.next++
+	return nil
+}
+
+// fakeConnector opens fakeConns that share its scripted errors and results.
+type fakeConnector struct {
+	driver.Connector
+	conn    *fakeConn // the last connection opened
+	errs    map[string][]error
+	results map[string]*fakeResult
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	c.conn = &fakeConn{errs: c.errs, results: c.results}
+	return c.conn, nil
+}
+
+// openFakeDB opens a database handle on fake with a single connection, as
+// workers use.
+func openFakeDB(t *testing.T, fake *fakeConnector, rec *stats.Recorder) *sql.DB {
+	db := sql.OpenDB(&countingConnector{Connector: fake, rec: rec})
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestConnectorSessionInit(t *testing.T) {
+	collector := stats.NewCollector()
+	fake := &fakeConnector{}
+	c := &countingConnector{Connector: fake, rec: collector.NewRecorder(), init: []string{"SET time_zone = '+00:00'", "USE app"}}
+
+	if _, err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	if len(fake.conn.executed) != 2 || fake.conn.executed[1] != "USE app" {
+		t.Errorf("unexpected init statements: %v", fake.conn.executed)
+	}
+
+	c.init = []string{"bad"}
+	_, err := c.Connect(context.Background())
+	var initErr *initError
+	if !errors.As(err, &initErr) || initErr.stmt != "bad" {
+		t.Errorf("expected an init error for bad, got %v", err)
+	}
+	if !fake.conn.closed {
+		t.Errorf("expected the connection to be closed after a failed init")
+	}
+	if snap := collector.Collect(); snap.Connections != 2 {
+		t.Errorf("expected 2 connections, got %d", snap.Connections)
+	}
+}
@@ -0,0 +1,143 @@
+package worker
+
+import (
+	"context"
+	"database_workload/config"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// PacerOptions selects the load model of a Pacer.
+type PacerOptions struct {
+	// OpenLoop keeps slots that were missed because no worker was idle, so
+	// that sessions start as soon as possible after their intended start
+	// instead of being silently skipped.
+	OpenLoop bool
+	// Poisson spaces slots with exponentially distributed intervals instead
+	// of a constant interval.
+	Poisson bool
+}
+
+// PacerOptionsFrom returns the load model selected by cfg.
+func PacerOptionsFrom(cfg *config.Config) PacerOptions {
+	return PacerOptions{
+		OpenLoop: cfg.LoadModel == "open",
+		Poisson:  cfg.Arrival == "poisson",
+	}
+}
+
+// Pacer hands out session start slots at a target rate. It is safe for
+// concurrent use: when shared by all workers, whichever worker is idle takes
+// the next slot, so the aggregate rate holds even if some workers are stuck
+// on slow queries.
+type Pacer struct {
+	mu     sync.Mutex
+	rateAt func(elapsed time.Duration) float64
+	opts   PacerOptions
+	start  time.Time
+	// last is the last slot handed out, zero before the first one and
+	// after a pause.
+	last time.Time
+	// gap is the interval from last to the next slot, in slots: 1 for a
+	// constant interval, an exponential draw for Poisson arrivals.
+	gap float64
+}
+
+// idlePoll is how often a Pacer re-evaluates a schedule whose rate is zero,
+// or whose next slot is still far off.
+const idlePoll = 100 * time.Millisecond
+
+// NewPacer creates a Pacer handing out rate slots per second.
+func NewPacer(rate float64, opts PacerOptions) *Pacer {
+	return &Pacer{
+		rateAt: func(time.Duration) float64 { return rate },
+		opts:   opts,
+	}
+}
+
+// NewScheduledPacer creates a Pacer whose rate follows schedule, starting
+// from the first call to Wait.
+func NewScheduledPacer(schedule *config.RateSchedule, opts PacerOptions) *Pacer {
+	return &Pacer{
+		rateAt: func(elapsed time.Duration) float64 { return RateAt(schedule, elapsed) },
+		opts:   opts,
+	}
+}
+
+// Wait blocks until the next slot and returns its intended start time.
+// In closed-loop mode, slots missed because no worker was idle are dropped
+// rather than run in a burst later. It returns false if ctx is cancelled
+// first.
+func (p *Pacer) Wait(ctx context.Context) (time.Time, bool) {
+	for {
+		p.mu.Lock()
+		now := time.Now()
+		if p.start.IsZero() {
+			p.start = now
+		}
+		rate := p.rateAt(now.Sub(p.start))
+		if rate <= 0 {
+			// Paused: hand out nothing, build no backlog, and check the
+			// schedule again later.
+			p.last = time.Time{}
+			p.mu.Unlock()
+			if !sleepUntil(ctx, now.Add(idlePoll)) {
+				return now, false
+			}
+			continue
+		}
+		slot := now
+		if !p.last.IsZero() {
+			interval := p.gap * float64(time.Second) / rate
+			if interval > float64(now.Sub(p.last)+idlePoll) {
+				// The next slot is far off at the current rate; check again
+				// later, as a rising schedule brings it closer.
+				p.mu.Unlock()
+				if !sleepUntil(ctx, now.Add(idlePoll)) {
+					return now, false
+				}
+				continue
+			}
+			slot = p.last.Add(time.Duration(interval))
+			if !p.opts.OpenLoop && slot.Before(now) {
+				slot = now
+			}
+		}
+		p.last = slot
+		p.gap = p.nextGap()
+		p.mu.Unlock()
+
+		return slot, sleepUntil(ctx, slot)
+	}
+}
+
+// SetRate replaces the rate or schedule of p with a constant rate.
+func (p *Pacer) SetRate(rate float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rateAt = func(time.Duration) float64 { return rate }
+}
+
+func (p *Pacer) nextGap() float64 {
+	if p.opts.Poisson {
+		return rand.ExpFloat64()
+	}
+	return 1
+}
+
+// sleepUntil waits until t and returns false if ctx is cancelled first.
+func sleepUntil(ctx context.Context, t time.Time) bool {
+	d := time.Until(t)
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
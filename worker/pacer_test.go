@@ -0,0 +1,86 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPacerSharedRate(t *testing.T) {
+	p := NewPacer(1000, PacerOptions{})
+	ctx := context.Background()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				p.Wait(ctx)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 100 slots at 1000/s take at least 99ms no matter how many workers wait.
+	if elapsed := time.Since(start); elapsed < 99*time.Millisecond {
+		t.Errorf("100 slots at 1000/s took only %v", elapsed)
+	}
+}
+
+func TestPacerDropsMissedSlots(t *testing.T) {
+	p := NewPacer(100, PacerOptions{})
+	ctx := context.Background()
+	p.Wait(ctx)
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	p.Wait(ctx)
+	p.Wait(ctx)
+	if elapsed := time.Since(start); elapsed < 9*time.Millisecond {
+		t.Errorf("expected missed slots to be dropped instead of burst, took %v", elapsed)
+	}
+}
+
+func TestPacerCancel(t *testing.T) {
+	p := NewPacer(0.001, PacerOptions{})
+	ctx, cancel := context.WithCancel(context.Background())
+	p.Wait(ctx)
+	cancel()
+	if _, ok := p.Wait(ctx); ok {
+		t.Errorf("expected Wait to return false once the context is cancelled")
+	}
+}
+
+func TestPacerOpenLoopKeepsMissedSlots(t *testing.T) {
+	p := NewPacer(100, PacerOptions{OpenLoop: true})
+	ctx := context.Background()
+	first, _ := p.Wait(ctx)
+	time.Sleep(50 * time.Millisecond)
+
+	// The missed slots are handed out immediately, with their intended times.
+	start := time.Now()
+	for i := 1; i <= 3; i++ {
+		slot, _ := p.Wait(ctx)
+		if want := first.Add(time.Duration(i) * 10 * time.Millisecond); !slot.Equal(want) {
+			t.Errorf("slot %d: expected intended start %v, got %v", i, want, slot)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Millisecond {
+		t.Errorf("expected overdue slots without waiting, took %v", elapsed)
+	}
+}
+
+func TestPacerPoissonRate(t *testing.T) {
+	p := NewPacer(1000, PacerOptions{Poisson: true})
+	var total float64
+	for i := 0; i < 10000; i++ {
+		total += p.nextGap()
+	}
+	mean := total / 10000
+	if mean < 0.9 || mean > 1.1 {
+		t.Errorf("expected a mean gap of about 1 slot, got %v", mean)
+	}
+}
@@ -0,0 +1,91 @@
+package worker
+
+import (
+	"database_workload/config"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// defaultRetryCodes are retried when the policy lists no codes: TiDB write
+// conflict, deadlock and lock wait timeout.
+var defaultRetryCodes = []uint16{9007, 1213, 1205}
+
+const (
+	defaultRetryBackoff    = 10 * time.Millisecond
+	defaultRetryMaxBackoff = time.Second
+)
+
+// retryPolicy decides whether and when an aborted transaction is retried.
+type retryPolicy struct {
+	codes       map[uint16]bool
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+}
+
+// newRetryPolicy returns the policy configured by cfg, or nil if retries are
+// disabled.
+func newRetryPolicy(cfg *config.RetryPolicy) *retryPolicy {
+	if cfg == nil {
+		return nil
+	}
+	p := &retryPolicy{
+		codes:       make(map[uint16]bool),
+		maxAttempts: cfg.MaxAttempts,
+		backoff:     time.Duration(cfg.Backoff),
+		maxBackoff:  time.Duration(cfg.MaxBackoff),
+	}
+	codes := cfg.Codes
+	if len(codes) == 0 {
+		codes = defaultRetryCodes
+	}
+	for _, c := range codes {
+		p.codes[c] = true
+	}
+	if p.backoff <= 0 {
+		p.backoff = defaultRetryBackoff
+	}
+	if p.maxBackoff <= 0 {
+		p.maxBackoff = defaultRetryMaxBackoff
+	}
+	if p.maxBackoff < p.backoff {
+		p.maxBackoff = p.backoff
+	}
+	return p
+}
+
+// shouldRetry reports whether a session that failed with err on the given
+// attempt (starting at 1) is retried.
+func (p *retryPolicy) shouldRetry(attempt int, err error) bool {
+	if p == nil || attempt >= p.maxAttempts {
+		return false
+	}
+	code, ok := errorNumber(err)
+	return ok && p.codes[code]
+}
+
+// errorNumber returns the MySQL error number of err, if it has one.
+func errorNumber(err error) (uint16, bool) {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number, true
+	}
+	return 0, false
+}
+
+// delay returns the backoff before the retry following the given attempt:
+// a random duration up to the base backoff doubled for every previous retry
+// (full jitter), so that conflicting sessions do not retry in lockstep.
+func (p *retryPolicy) delay(attempt int) time.Duration {
+	ceiling := p.backoff
+	for i := 1; i < attempt && ceiling < p.maxBackoff; i++ {
+		ceiling *= 2
+	}
+	if ceiling > p.maxBackoff {
+		ceiling = p.maxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
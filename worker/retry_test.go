@@ -0,0 +1,46 @@
+package worker
+
+import (
+	"database_workload/config"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	p := newRetryPolicy(&config.RetryPolicy{MaxAttempts: 3})
+	conflict := &mysql.MySQLError{Number: 9007}
+
+	if !p.shouldRetry(1, conflict) || !p.shouldRetry(2, fmt.Errorf("commit: %w", conflict)) {
+		t.Errorf("expected a write conflict to be retried")
+	}
+	if p.shouldRetry(3, conflict) {
+		t.Errorf("expected no retry after the last attempt")
+	}
+	if p.shouldRetry(1, &mysql.MySQLError{Number: 1062}) || p.shouldRetry(1, errors.New("boom")) {
+		t.Errorf("expected other errors not to be retried")
+	}
+
+	var disabled *retryPolicy
+	if disabled.shouldRetry(1, conflict) {
+		t.Errorf("expected no retry without a policy")
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := newRetryPolicy(&config.RetryPolicy{
+		MaxAttempts: 10,
+		Backoff:     config.Duration(10 * time.Millisecond),
+		MaxBackoff:  config.Duration(40 * time.Millisecond),
+	})
+	for attempt, ceiling := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 5: 40 * time.Millisecond} {
+		for i := 0; i < 100; i++ {
+			if d := p.delay(attempt); d < 0 || d > ceiling {
+				t.Fatalf("attempt %d: delay %v out of range [0, %v]", attempt, d, ceiling)
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package worker
+
+import (
+	"database_workload/config"
+	"math"
+	"time"
+)
+
+// RateAt returns the target rate of s at elapsed time since the start of
+// the run.
+func RateAt(s *config.RateSchedule, elapsed time.Duration) float64 {
+	switch s.Type {
+	case "ramp":
+		d := time.Duration(s.Duration)
+		if elapsed >= d {
+			return s.To
+		}
+		return s.From + (s.To-s.From)*float64(elapsed)/float64(d)
+	case "step":
+		steps := math.Floor(float64(elapsed) / float64(s.Every))
+		rate := s.From + s.Step*steps
+		if s.To > 0 && ((s.Step > 0 && rate > s.To) || (s.Step < 0 && rate < s.To)) {
+			return s.To
+		}
+		return rate
+	case "sine":
+		return s.Base + s.Amplitude*math.Sin(2*math.Pi*float64(elapsed)/float64(s.Period))
+	case "points":
+		return interpolate(s.Points, elapsed)
+	default:
+		return 0
+	}
+}
+
+func interpolate(points []config.RatePoint, elapsed time.Duration) float64 {
+	if elapsed <= time.Duration(points[0].At) {
+		return points[0].Rate
+	}
+	for i := 1; i < len(points); i++ {
+		prev, next := points[i-1], points[i]
+		if elapsed < time.Duration(next.At) {
+			frac := float64(elapsed-time.Duration(prev.At)) / float64(next.At-prev.At)
+			return prev.Rate + (next.Rate-prev.Rate)*frac
+		}
+	}
+	return points[len(points)-1].Rate
+}
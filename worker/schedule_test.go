@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"context"
+	"database_workload/config"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRateAt(t *testing.T) {
+	minute := config.Duration(time.Minute)
+	tests := []struct {
+		name     string
+		schedule config.RateSchedule
+		elapsed  time.Duration
+		want     float64
+	}{
+		{"ramp start", config.RateSchedule{Type: "ramp", From: 100, To: 500, Duration: minute}, 0, 100},
+		{"ramp middle", config.RateSchedule{Type: "ramp", From: 100, To: 500, Duration: minute}, 30 * time.Second, 300},
+		{"ramp end", config.RateSchedule{Type: "ramp", From: 100, To: 500, Duration: minute}, time.Hour, 500},
+		{"step", config.RateSchedule{Type: "step", From: 100, Step: 50, Every: minute}, 150 * time.Second, 200},
+		{"step capped", config.RateSchedule{Type: "step", From: 100, Step: 50, Every: minute, To: 150}, time.Hour, 150},
+		{"sine peak", config.RateSchedule{Type: "sine", Base: 100, Amplitude: 50, Period: config.Duration(4 * time.Minute)}, time.Minute, 150},
+		{"sine trough", config.RateSchedule{Type: "sine", Base: 100, Amplitude: 50, Period: config.Duration(4 * time.Minute)}, 3 * time.Minute, 50},
+		{"points before", config.RateSchedule{Type: "points", Points: []config.RatePoint{{At: minute, Rate: 100}, {At: 3 * minute, Rate: 300}}}, 0, 100},
+		{"points between", config.RateSchedule{Type: "points", Points: []config.RatePoint{{At: minute, Rate: 100}, {At: 3 * minute, Rate: 300}}}, 2 * time.Minute, 200},
+		{"points after", config.RateSchedule{Type: "points", Points: []config.RatePoint{{At: minute, Rate: 100}, {At: 3 * minute, Rate: 300}}}, time.Hour, 300},
+	}
+	for _, tc := range tests {
+		if got := RateAt(&tc.schedule, tc.elapsed); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestScheduledPacerPaused(t *testing.T) {
+	schedule := &config.RateSchedule{Type: "points", Points: []config.RatePoint{{At: 0, Rate: 0}}}
+	p := NewScheduledPacer(schedule, PacerOptions{OpenLoop: true})
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+
+	if _, ok := p.Wait(ctx); ok {
+		t.Errorf("expected no slot while the scheduled rate is zero")
+	}
+}
+
+func TestScheduledPacerRampFromZero(t *testing.T) {
+	// The first slot comes at a rate near zero; the next one must follow the
+	// ramp instead of waiting the ~6s interval of that rate.
+	schedule := &config.RateSchedule{Type: "ramp", From: 0, To: 100, Duration: config.Duration(time.Minute)}
+	p := NewScheduledPacer(schedule, PacerOptions{})
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if _, ok := p.Wait(ctx); !ok {
+			t.Fatalf("expected slot %d within 3s", i)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 1500*time.Millisecond {
+		t.Errorf("expected 2 slots within 1.5s of a ramp from 0, took %v", elapsed)
+	}
+}
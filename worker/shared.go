@@ -0,0 +1,41 @@
+package worker
+
+import (
+	"database_workload/stats"
+	"sync/atomic"
+)
+
+// Shared holds the state shared by all workers of a run.
+type Shared struct {
+	Collector *stats.Collector
+	// Sessions limits the number of sessions run by all workers together.
+	// nil means unlimited.
+	Sessions *Budget
+	// Pacer paces the session starts of all workers together to hold a
+	// target aggregate rate. nil means unpaced.
+	Pacer *Pacer
+}
+
+// Budget is a number of sessions that workers draw from.
+type Budget struct {
+	remaining atomic.Int64
+}
+
+// NewBudget creates a Budget of n sessions, or nil (unlimited) if n <= 0.
+func NewBudget(n int64) *Budget {
+	if n <= 0 {
+		return nil
+	}
+	b := &Budget{}
+	b.remaining.Store(n)
+	return b
+}
+
+// take reserves one session and reports whether the budget allowed it.
+// A nil Budget is unlimited.
+func (b *Budget) take() bool {
+	if b == nil {
+		return true
+	}
+	return b.remaining.Add(-1) >= 0
+}
@@ -0,0 +1,178 @@
+package worker
+
+import (
+	"database/sql"
+	"database_workload/config"
+	"database_workload/generator"
+	"database_workload/sqltext"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// txPlan is a transaction type prepared for execution by one worker.
+type txPlan struct {
+	name      string
+	templates []config.Template
+	stmts     []*sqltext.Statement
+	query     []bool // whether each template returns a result set
+	tolerated []map[uint16]bool
+	think     []generator.Generator // pause after each template, or nil
+	// sessionThink is the pause after the session, or nil.
+	sessionThink generator.Generator
+	params       [][]paramSource
+	labels       []string // stats label of each template
+	useTX        bool
+	txOptions    *sql.TxOptions
+	isolation    string                         // isolation level as SQL, e.g. "READ COMMITTED"
+	startTx      string                         // raw statement replacing BEGIN
+	vars         map[string]generator.Generator // session variables
+}
+
+var isolationLevels = map[string]sql.IsolationLevel{
+	"read_uncommitted": sql.LevelReadUncommitted,
+	"read_committed":   sql.LevelReadCommitted,
+	"repeatable_read":  sql.LevelRepeatableRead,
+	"serializable":     sql.LevelSerializable,
+}
+
+func newTxPlan(tx config.Transaction) (*txPlan, error) {
+	plan := &txPlan{
+		name:      tx.Name,
+		templates: tx.Templates,
+		stmts:     make([]*sqltext.Statement, len(tx.Templates)),
+		query:     make([]bool, len(tx.Templates)),
+		tolerated: make([]map[uint16]bool, len(tx.Templates)),
+		think:     make([]generator.Generator, len(tx.Templates)),
+		params:    make([][]paramSource, len(tx.Templates)),
+		labels:    make([]string, len(tx.Templates)),
+		useTX:     tx.UseTransaction,
+		startTx:   tx.StartTransaction,
+		vars:      make(map[string]generator.Generator, len(tx.SessionVars)),
+	}
+	if tx.ThinkTime != nil {
+		g, err := generator.NewNumberGenerator(tx.ThinkTime)
+		if err != nil {
+			return nil, fmt.Errorf("think_time: %w", err)
+		}
+		plan.sessionThink = g
+	}
+	if tx.IsolationLevel != "" || tx.ReadOnly {
+		plan.txOptions = &sql.TxOptions{Isolation: isolationLevels[tx.IsolationLevel], ReadOnly: tx.ReadOnly}
+	}
+	if tx.IsolationLevel != "" {
+		plan.isolation = strings.ToUpper(strings.ReplaceAll(tx.IsolationLevel, "_", " "))
+	}
+	for name, param := range tx.SessionVars {
+		p := param
+		g, err := generator.New(&p)
+		if err != nil {
+			return nil, fmt.Errorf("session variable %s: %w", name, err)
+		}
+		plan.vars[name] = g
+	}
+	for i, tmpl := range tx.Templates {
+		plan.labels[i] = strconv.Itoa(i)
+		if tx.Name != "" {
+			plan.labels[i] = tx.Name + "." + plan.labels[i]
+		}
+		stmt, err := sqltext.Parse(tmpl.SQL, tmpl.ParamNames())
+		if err != nil {
+			return nil, fmt.Errorf("template %d: %w", i, err)
+		}
+		plan.stmts[i] = stmt
+		plan.query[i] = tmpl.IsQuery()
+		codes, err := tmpl.ToleratedCodes()
+		if err != nil {
+			return nil, fmt.Errorf("template %d: %w", i, err)
+		}
+		if tmpl.ThinkTime != nil {
+			if plan.think[i], err = generator.NewNumberGenerator(tmpl.ThinkTime); err != nil {
+				return nil, fmt.Errorf("template %d: think_time: %w", i, err)
+			}
+		}
+		if len(codes) > 0 {
+			plan.tolerated[i] = make(map[uint16]bool, len(codes))
+			for _, c := range codes {
+				plan.tolerated[i][c] = true
+			}
+		}
+		plan.params[i] = make([]paramSource, len(tmpl.Params))
+		for j, param := range tmpl.Params {
+			// newParamSource takes a copy of the param to avoid issues with pointers
+			src, err := newParamSource(param)
+			if err != nil {
+				return nil, err
+			}
+			plan.params[i][j] = src
+		}
+	}
+	return plan, nil
+}
+
+// statement generates the param values of template i and returns the SQL
+// and driver args to execute.
+func (plan *txPlan) statement(i int, vars sessionVars) (string, []interface{}) {
+	values := make([]interface{}, len(plan.params[i]))
+	for j, src := range plan.params[i] {
+		values[j] = src.value(vars)
+	}
+	return plan.stmts[i].Expand(values)
+}
+
+// thinkTime returns a pause drawn from g, in milliseconds.
+func thinkTime(g generator.Generator) time.Duration {
+	ms, _ := g.Generate().(int64)
+	return time.Duration(ms) * time.Millisecond
+}
+
+// tolerates reports whether template i tolerates err.
+func (plan *txPlan) tolerates(i int, err error) bool {
+	code, ok := errorNumber(err)
+	return ok && plan.tolerated[i][code]
+}
+
+// newSessionVars generates the session variables of plan for a new session.
+func (plan *txPlan) newSessionVars() sessionVars {
+	vars := make(sessionVars, len(plan.vars))
+	for name, g := range plan.vars {
+		vars[name] = g.Generate()
+	}
+	return vars
+}
+
+// txPicker chooses a transaction type by weight.
+type txPicker struct {
+	plans   []*txPlan
+	weights []float64 // cumulative weights
+	total   float64
+}
+
+func newTxPicker(txs []config.Transaction) (*txPicker, error) {
+	p := &txPicker{}
+	for _, tx := range txs {
+		plan, err := newTxPlan(tx)
+		if err != nil {
+			return nil, err
+		}
+		p.plans = append(p.plans, plan)
+		p.total += tx.Weight
+		p.weights = append(p.weights, p.total)
+	}
+	return p, nil
+}
+
+func (p *txPicker) pick() *txPlan {
+	if len(p.plans) == 1 {
+		return p.plans[0]
+	}
+	r := rand.Float64() * p.total
+	for i, w := range p.weights {
+		if r < w {
+			return p.plans[i]
+		}
+	}
+	return p.plans[len(p.plans)-1]
+}
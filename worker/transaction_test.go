@@ -0,0 +1,150 @@
+package worker
+
+import (
+	"database/sql"
+	"database_workload/config"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestTxPickerWeights(t *testing.T) {
+	picker, err := newTxPicker([]config.Transaction{
+		{Name: "point_select", Weight: 70, Templates: []config.Template{{SQL: "SELECT 1"}}},
+		{Name: "update", Weight: 20, Templates: []config.Template{{SQL: "UPDATE t SET a = 1"}}},
+		{Name: "insert", Weight: 10, Templates: []config.Template{{SQL: "INSERT INTO t VALUES (1)"}}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create picker: %v", err)
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		counts[picker.pick().name]++
+	}
+	if counts["point_select"] < 6500 || counts["point_select"] > 7500 {
+		t.Errorf("unexpected count for point_select: %d", counts["point_select"])
+	}
+	if counts["update"] < 1500 || counts["update"] > 2500 {
+		t.Errorf("unexpected count for update: %d", counts["update"])
+	}
+	if counts["insert"] < 500 || counts["insert"] > 1500 {
+		t.Errorf("unexpected count for insert: %d", counts["insert"])
+	}
+}
+
+func TestTxPlanLabels(t *testing.T) {
+	templates := []config.Template{{SQL: "SELECT 1"}, {SQL: "SELECT 2"}}
+
+	named, err := newTxPlan(config.Transaction{Name: "report", Templates: templates})
+	if err != nil {
+		t.Fatalf("failed to create plan: %v", err)
+	}
+	if named.labels[1] != "report.1" {
+		t.Errorf("expected label report.1, got %s", named.labels[1])
+	}
+
+	unnamed, err := newTxPlan(config.Transaction{Templates: templates})
+	if err != nil {
+		t.Fatalf("failed to create plan: %v", err)
+	}
+	if unnamed.labels[1] != "1" {
+		t.Errorf("expected label 1, got %s", unnamed.labels[1])
+	}
+}
+
+func TestTxPlanSessionVars(t *testing.T) {
+	min, max := int64(1), int64(1000000000)
+	name := "user_id"
+	varParam := config.Param{Type: "var", Var: &name}
+	plan, err := newTxPlan(config.Transaction{
+		SessionVars: map[string]config.Param{
+			"user_id": {Type: "number", RandomMode: "uniform", Min: &min, Max: &max},
+		},
+		Templates: []config.Template{
+			{SQL: "INSERT INTO t (user_id) VALUES (?)", Params: []config.Param{varParam}},
+			{SQL: "SELECT * FROM t WHERE user_id = ?", Params: []config.Param{varParam}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create plan: %v", err)
+	}
+
+	first := plan.newSessionVars()
+	insert := plan.params[0][0].value(first)
+	if insert == nil || insert != plan.params[1][0].value(first) {
+		t.Errorf("templates of one session got different values: %v, %v", insert, plan.params[1][0].value(first))
+	}
+	// A new session generates new values; with this range a collision is
+	// practically impossible.
+	if second := plan.newSessionVars(); plan.params[0][0].value(second) == insert {
+		t.Errorf("two sessions got the same value: %v", insert)
+	}
+}
+
+func TestTxPlanTolerates(t *testing.T) {
+	plan, err := newTxPlan(config.Transaction{Templates: []config.Template{
+		{SQL: "INSERT INTO t VALUES (1)", ToleratedErrors: []string{"duplicate_key", "1364"}},
+		{SQL: "INSERT INTO t VALUES (2)"},
+	}})
+	if err != nil {
+		t.Fatalf("failed to create plan: %v", err)
+	}
+	if !plan.tolerates(0, &mysql.MySQLError{Number: 1062}) || !plan.tolerates(0, &mysql.MySQLError{Number: 1364}) {
+		t.Errorf("expected template 0 to tolerate 1062 and 1364")
+	}
+	if plan.tolerates(0, &mysql.MySQLError{Number: 1213}) || plan.tolerates(0, errors.New("boom")) {
+		t.Errorf("expected template 0 not to tolerate other errors")
+	}
+	if plan.tolerates(1, &mysql.MySQLError{Number: 1062}) {
+		t.Errorf("expected template 1 to tolerate nothing")
+	}
+}
+
+func TestTxPlanTxOptions(t *testing.T) {
+	plan, err := newTxPlan(config.Transaction{UseTransaction: true, IsolationLevel: "read_committed", ReadOnly: true})
+	if err != nil {
+		t.Fatalf("failed to create plan: %v", err)
+	}
+	if plan.txOptions == nil || plan.txOptions.Isolation != sql.LevelReadCommitted || !plan.txOptions.ReadOnly {
+		t.Errorf("unexpected tx options: %+v", plan.txOptions)
+	}
+	if plan.isolation != "READ COMMITTED" {
+		t.Errorf("unexpected isolation level: %s", plan.isolation)
+	}
+
+	plain, err := newTxPlan(config.Transaction{UseTransaction: true})
+	if err != nil {
+		t.Fatalf("failed to create plan: %v", err)
+	}
+	if plain.txOptions != nil {
+		t.Errorf("expected driver defaults, got %+v", plain.txOptions)
+	}
+}
+
+func TestTxPlanThinkTime(t *testing.T) {
+	value := int64(25)
+	fixed := &config.Param{RandomMode: "fixed", Value: &value}
+	plan, err := newTxPlan(config.Transaction{
+		ThinkTime: fixed,
+		Templates: []config.Template{{SQL: "SELECT 1", ThinkTime: fixed}, {SQL: "SELECT 2"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create plan: %v", err)
+	}
+	if plan.think[0] == nil || thinkTime(plan.think[0]) != 25*time.Millisecond {
+		t.Errorf("expected a 25ms think time after template 0")
+	}
+	if plan.think[1] != nil {
+		t.Errorf("expected no think time after template 1")
+	}
+	if plan.sessionThink == nil || thinkTime(plan.sessionThink) != 25*time.Millisecond {
+		t.Errorf("expected a 25ms think time after the session")
+	}
+
+	if _, err := newTxPlan(config.Transaction{ThinkTime: &config.Param{RandomMode: "exponential"}}); err == nil {
+		t.Errorf("expected an error for an exponential think time without mean")
+	}
+}
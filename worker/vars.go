@@ -0,0 +1,139 @@
+package worker
+
+import (
+	"database/sql"
+	"database_workload/config"
+	"database_workload/generator"
+	"fmt"
+	"math/rand"
+)
+
+// sessionVars holds the variables captured during one session.
+type sessionVars map[string]interface{}
+
+// clone returns a copy of vars.
+func (vars sessionVars) clone() sessionVars {
+	c := make(sessionVars, len(vars))
+	for name, v := range vars {
+		c[name] = v
+	}
+	return c
+}
+
+// paramSource produces the value of one template parameter.
+type paramSource interface {
+	value(vars sessionVars) interface{}
+}
+
+// generatorSource produces a fresh random value for every execution.
+type generatorSource struct {
+	gen generator.Generator
+}
+
+func (s generatorSource) value(sessionVars) interface{} {
+	return s.gen.Generate()
+}
+
+// varSource produces the current value of a session variable.
+type varSource struct {
+	name string
+}
+
+func (s varSource) value(vars sessionVars) interface{} {
+	return vars[s.name]
+}
+
+func newParamSource(p config.Param) (paramSource, error) {
+	if p.Type == "var" {
+		if p.Var == nil {
+			return nil, fmt.Errorf("var param requires var")
+		}
+		return varSource{name: *p.Var}, nil
+	}
+	g, err := generator.New(&p)
+	if err != nil {
+		return nil, err
+	}
+	return generatorSource{gen: g}, nil
+}
+
+// drainRows reads the whole result set, stores the captured columns in vars
+// and returns the number of rows read. The result must be drained even
+// without captures, to avoid "connection reset by peer" errors.
+func drainRows(rows *sql.Rows, captures []config.Capture, vars sessionVars) (int64, error) {
+	var n int64
+	if len(captures) == 0 {
+		for rows.Next() {
+			n++
+		}
+		return n, rows.Err()
+	}
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	idx := make([]int, len(captures))
+	for i, c := range captures {
+		idx[i] = -1
+		for j, col := range cols {
+			if col == c.Column {
+				idx[i] = j
+				break
+			}
+		}
+		if idx[i] < 0 {
+			return 0, fmt.Errorf("captured column %s is not in the result", c.Column)
+		}
+	}
+
+	raw := make([]interface{}, len(cols))
+	dest := make([]interface{}, len(cols))
+	for i := range dest {
+		dest[i] = &raw[i]
+	}
+	picked := make([]interface{}, len(captures))
+	all := make([][]interface{}, len(captures))
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, err
+		}
+		n++
+		// Reservoir sampling: every row is kept with probability 1/n, and all
+		// random captures are taken from the same row.
+		keep := rand.Int63n(n) == 0
+		for i, c := range captures {
+			v := raw[idx[i]]
+			if b, ok := v.([]byte); ok {
+				v = string(b)
+			}
+			switch c.Mode {
+			case "all":
+				all[i] = append(all[i], v)
+			case "random":
+				if keep {
+					picked[i] = v
+				}
+			default:
+				if n == 1 {
+					picked[i] = v
+				}
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+
+	for i, c := range captures {
+		if c.Mode == "all" {
+			if all[i] == nil {
+				all[i] = []interface{}{}
+			}
+			vars[c.Var] = all[i]
+		} else {
+			vars[c.Var] = picked[i]
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,126 @@
+package worker
+
+import (
+	"context"
+	"database/sql/driver"
+	"database_workload/config"
+	"database_workload/stats"
+	"reflect"
+	"testing"
+)
+
+const ordersQuery = "SELECT id, status FROM orders"
+
+// queryOrders runs ordersQuery against a fake database returning rows, and
+// drains its result with captures into vars.
+func queryOrders(t *testing.T, rows [][]driver.Value, captures []config.Capture, vars sessionVars) (int64, error) {
+	fake := &fakeConnector{results: map[string]*fakeResult{
+		ordersQuery: {columns: []string{"id", "status"}, rows: rows},
+	}}
+	db := openFakeDB(t, fake, stats.NewCollector().NewRecorder())
+	result, err := db.QueryContext(context.Background(), ordersQuery)
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	defer result.Close()
+	return drainRows(result, captures, vars)
+}
+
+var orderRows = [][]driver.Value{
+	{int64(1), []byte("new")},
+	{int64(2), []byte("paid")},
+	{int64(3), []byte("shipped")},
+}
+
+func TestDrainRows(t *testing.T) {
+	n, err := queryOrders(t, orderRows, nil, sessionVars{})
+	if err != nil || n != 3 {
+		t.Errorf("expected 3 rows, got %d (%v)", n, err)
+	}
+}
+
+func TestDrainRows_Capture(t *testing.T) {
+	vars := sessionVars{}
+	n, err := queryOrders(t, orderRows, []config.Capture{
+		{Column: "id", Var: "first_id"},
+		{Column: "status", Var: "first_status", Mode: "first"},
+		{Column: "id", Var: "ids", Mode: "all"},
+	}, vars)
+	if err != nil {
+		t.Fatalf("drainRows failed: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("expected 3 rows, got %d", n)
+	}
+	if vars["first_id"] != int64(1) {
+		t.Errorf("expected first_id 1, got %v", vars["first_id"])
+	}
+	// Text columns are captured as strings, not as the driver's bytes.
+	if vars["first_status"] != "new" {
+		t.Errorf("expected first_status new, got %v", vars["first_status"])
+	}
+	if want := []interface{}{int64(1), int64(2), int64(3)}; !reflect.DeepEqual(vars["ids"], want) {
+		t.Errorf("expected ids %v, got %v", want, vars["ids"])
+	}
+}
+
+func TestDrainRows_CaptureRandom(t *testing.T) {
+	seen := make(map[interface{}]int)
+	for i := 0; i < 300; i++ {
+		vars := sessionVars{}
+		if _, err := queryOrders(t, orderRows, []config.Capture{{Column: "id", Var: "id", Mode: "random"}}, vars); err != nil {
+			t.Fatalf("drainRows failed: %v", err)
+		}
+		seen[vars["id"]]++
+	}
+	for _, id := range []int64{1, 2, 3} {
+		if seen[id] < 50 {
+			t.Errorf("expected id %d to be picked about 100 times, got %d", id, seen[id])
+		}
+	}
+	if len(seen) != 3 {
+		t.Errorf("unexpected values picked: %v", seen)
+	}
+}
+
+func TestDrainRows_CaptureRandomSameRow(t *testing.T) {
+	pairs := make(map[interface{}]interface{})
+	for _, row := range orderRows {
+		pairs[row[0]] = string(row[1].([]byte))
+	}
+	for i := 0; i < 100; i++ {
+		vars := sessionVars{}
+		if _, err := queryOrders(t, orderRows, []config.Capture{
+			{Column: "id", Var: "id", Mode: "random"},
+			{Column: "status", Var: "status", Mode: "random"},
+		}, vars); err != nil {
+			t.Fatalf("drainRows failed: %v", err)
+		}
+		if pairs[vars["id"]] != vars["status"] {
+			t.Fatalf("expected id and status from the same row, got %v and %v", vars["id"], vars["status"])
+		}
+	}
+}
+
+func TestDrainRows_CaptureEmpty(t *testing.T) {
+	vars := sessionVars{"id": int64(7), "ids": []interface{}{int64(7)}}
+	n, err := queryOrders(t, nil, []config.Capture{
+		{Column: "id", Var: "id", Mode: "random"},
+		{Column: "id", Var: "ids", Mode: "all"},
+	}, vars)
+	if err != nil || n != 0 {
+		t.Fatalf("expected 0 rows, got %d (%v)", n, err)
+	}
+	if v, ok := vars["id"]; !ok || v != nil {
+		t.Errorf("expected id to be set to NULL, got %v", v)
+	}
+	if ids, ok := vars["ids"].([]interface{}); !ok || ids == nil || len(ids) != 0 {
+		t.Errorf("expected ids to be an empty array, got %#v", vars["ids"])
+	}
+}
+
+func TestDrainRows_MissingColumn(t *testing.T) {
+	if _, err := queryOrders(t, orderRows, []config.Capture{{Column: "total", Var: "total"}}, sessionVars{}); err == nil {
+		t.Errorf("expected an error for a column missing from the result")
+	}
+}
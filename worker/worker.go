@@ -4,51 +4,51 @@ import (
 	"context"
 	"database/sql"
 	"database_workload/config"
-	"database_workload/generator"
+	"database_workload/stats"
 
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
 	"strings"
 	"time"
-
-	_ "github.com/go-sql-driver/mysql"
 )
 
 // Worker executes workloads.
 type Worker struct {
-	id         int
-	dbConnStr  string
-	templates  []config.Template
-	generators [][]generator.Generator
-	useTX      bool
-	rate       int
-	db         *sql.DB
+	id        int
+	dbConnStr string
+	txs       *txPicker
+	rate      int
+	pacer     *Pacer
+	retry     *retryPolicy
+	db        *sql.DB
+	rec       *stats.Recorder
+
+	budget      *Budget
+	maxSessions int64
+	sessions    int64
 }
 
-// New creates a new Worker.
-func New(id int, cfg *config.Config) (*Worker, error) {
-	gens := make([][]generator.Generator, len(cfg.Templates))
-	for i, tmpl := range cfg.Templates {
-		gens[i] = make([]generator.Generator, len(tmpl.Params))
-		for j, param := range tmpl.Params {
-			// Make a copy of the param to avoid issues with pointers
-			p := param
-			g, err := generator.New(&p)
-			if err != nil {
-				return nil, err
-			}
-			gens[i][j] = g
-		}
+// New creates a new Worker. Its measurements are reported to shared.Collector.
+func New(id int, cfg *config.Config, shared *Shared) (*Worker, error) {
+	txs, err := newTxPicker(cfg.GetTransactions())
+	if err != nil {
+		return nil, err
+	}
+
+	pacer := shared.Pacer
+	if cfg.RatePerThread > 0 {
+		pacer = NewPacer(float64(cfg.RatePerThread), PacerOptionsFrom(cfg))
 	}
 
+	rec := shared.Collector.NewRecorder()
 	var db *sql.DB
-	var err error
 
 	if cfg.ConnectionType == "short" {
 		// Short-lived connections: force tcp-reuse and no idle connections.
 		dsn := strings.Replace(cfg.DBConnStr, "tcp(", "tcp-reuse(", 1)
-		db, err = sql.Open("mysql", dsn)
+		db, err = openDB(dsn, rec, cfg.SessionInit)
 		if err != nil {
 			log.Printf("Worker %d: ERROR failed to open DB connection: %v", id, err)
 			return nil, err
@@ -57,7 +57,7 @@ func New(id int, cfg *config.Config) (*Worker, error) {
 		db.SetMaxIdleConns(0)
 	} else {
 		// Default to long-lived connections with a pool of 1.
-		db, err = sql.Open("mysql", cfg.DBConnStr)
+		db, err = openDB(cfg.DBConnStr, rec, cfg.SessionInit)
 		if err != nil {
 			log.Printf("Worker %d: ERROR failed to open DB connection: %v", id, err)
 			return nil, err
@@ -68,17 +68,22 @@ func New(id int, cfg *config.Config) (*Worker, error) {
 	}
 
 	return &Worker{
-		id:         id,
-		dbConnStr:  cfg.DBConnStr,
-		templates:  cfg.Templates,
-		generators: gens,
-		useTX:      cfg.UseTransaction,
-		rate:       cfg.RatePerThread,
-		db:         db,
+		id:        id,
+		dbConnStr: cfg.DBConnStr,
+		txs:       txs,
+		rate:      cfg.RatePerThread,
+		pacer:     pacer,
+		retry:     newRetryPolicy(cfg.Retry),
+		db:        db,
+		rec:       rec,
+
+		budget:      shared.Sessions,
+		maxSessions: cfg.MaxSessionsPerWorker,
 	}, nil
 }
 
-// Run starts the worker's loop. It stops when the context is cancelled.
+// Run starts the worker's loop. It stops when the context is cancelled or
+// when its session limit or the shared session budget is exhausted.
 func (w *Worker) Run(ctx context.Context) {
 	wait := time.Millisecond * time.Duration(100*rand.Float64())
 	if w.rate > 0 {
@@ -86,12 +91,12 @@ func (w *Worker) Run(ctx context.Context) {
 		wait = time.Duration(float64(wait) * (rand.Float64()))
 	}
 	time.Sleep(wait)
-	var rateLimiter *time.Ticker
 	rateExplain := "no limit"
-	if w.rate > 0 {
-		rateLimiter = time.NewTicker(time.Second / time.Duration(w.rate))
-		defer rateLimiter.Stop()
+	switch {
+	case w.pacer != nil && w.rate > 0:
 		rateExplain = fmt.Sprintf("%d TPS", w.rate)
+	case w.pacer != nil:
+		rateExplain = "shared target TPS"
 	}
 
 	log.Printf("Worker %d started, rate: %s", w.id, rateExplain)
@@ -101,114 +106,211 @@ func (w *Worker) Run(ctx context.Context) {
 			log.Printf("Worker %d stopping", w.id)
 			return
 		default:
-			w.runSession(ctx)
-			if rateLimiter != nil {
-				<-rateLimiter.C
+			if w.maxSessions > 0 && w.sessions >= w.maxSessions {
+				log.Printf("Worker %d reached its limit of %d sessions", w.id, w.maxSessions)
+				return
+			}
+			var intended time.Time
+			if w.pacer != nil {
+				var ok bool
+				if intended, ok = w.pacer.Wait(ctx); !ok {
+					continue
+				}
 			}
+			if !w.budget.take() {
+				log.Printf("Worker %d stopping, session budget exhausted", w.id)
+				return
+			}
+			w.runSession(ctx, intended)
+			w.sessions++
+		}
+	}
+}
+
+// runSession runs one session of a transaction type chosen by weight and
+// records it. A transaction aborted by a retryable error is run again, and
+// the session only fails once the retries are exhausted. For paced sessions,
+// intended is the scheduled start time: latency is measured from it rather
+// than from the actual start, so that a stalled database cannot hide its own
+// delay (coordinated omission).
+func (w *Worker) runSession(ctx context.Context, intended time.Time) {
+	start := time.Now()
+	if !intended.IsZero() {
+		w.rec.RecordLag(start.Sub(intended))
+		start = intended
+	}
+	plan := w.txs.pick()
+	// Every attempt starts from a copy of the generated variables, so that
+	// captures of a failed attempt do not leak into the next one.
+	vars := plan.newSessionVars()
+	err := w.execSession(ctx, plan, vars.clone())
+	for attempt := 1; err != nil && plan.useTX && ctx.Err() == nil && w.retry.shouldRetry(attempt, err); attempt++ {
+		w.rec.RecordRetry(plan.name, err)
+		if !sleepUntil(ctx, time.Now().Add(w.retry.delay(attempt))) {
+			break
 		}
+		err = w.execSession(ctx, plan, vars.clone())
+	}
+	if ctx.Err() != nil {
+		// Errors caused by shutdown are not workload errors.
+		return
+	}
+	w.rec.RecordSession(plan.name, time.Since(start), err)
+
+	if plan.sessionThink != nil {
+		sleepUntil(ctx, time.Now().Add(thinkTime(plan.sessionThink)))
 	}
 }
 
-func (w *Worker) runSession(ctx context.Context) {
+// execSession runs the templates of plan once on a fresh connection and
+// records every error it encounters. A non-nil error means the session was
+// aborted.
+func (w *Worker) execSession(ctx context.Context, plan *txPlan, vars sessionVars) error {
 	conn, err := w.db.Conn(ctx)
+	var initErr *initError
+	if errors.As(err, &initErr) {
+		log.Printf("Worker %d: ERROR %v", w.id, err)
+		if ctx.Err() == nil {
+			w.rec.RecordInitError(initErr.err)
+		}
+		return err
+	}
 	if err != nil {
 		log.Printf("Worker %d: ERROR failed to get DB connection: %v", w.id, err)
-		return
+		w.recordError(ctx, err)
+		return err
 	}
 	defer conn.Close()
 
-	var tx *sql.Tx
-	if w.useTX {
-		tx, err = conn.BeginTx(ctx, nil)
+	var db execer = conn
+	var tx endTx
+	if plan.useTX {
+		db, tx, err = begin(ctx, conn, plan)
 		if err != nil {
 			log.Printf("Worker %d: ERROR failed to begin transaction: %v", w.id, err)
-			return
+			w.recordError(ctx, err)
+			return err
 		}
 	}
 
-	for i, tmpl := range w.templates {
+	for i, tmpl := range plan.templates {
 		repeatTimes := tmpl.GetRepeat()
 		for r := 0; r < repeatTimes; r++ {
 
-			args := make([]interface{}, len(tmpl.Params))
-			for j, gen := range w.generators[i] {
-				args[j] = gen.Generate()
-			}
-
-			finalSQL, finalArgs := handleArrayParams(tmpl.SQL, args)
-
-			isSelect := strings.HasPrefix(strings.TrimSpace(strings.ToUpper(finalSQL)), "SELECT")
+			finalSQL, finalArgs := plan.statement(i, vars)
 
-			if isSelect {
+			var n int64
+			stmtStart := time.Now()
+			if plan.query[i] {
 				var rows *sql.Rows
-				if w.useTX {
-					rows, err = tx.QueryContext(ctx, finalSQL, finalArgs...)
-				} else {
-					rows, err = conn.QueryContext(ctx, finalSQL, finalArgs...)
-				}
-
+				rows, err = db.QueryContext(ctx, finalSQL, finalArgs...)
 				if err == nil {
-					// read all result data to fix "connection reset by peer" error
-					for rows.Next() {
-					}
-					err = rows.Err()
+					n, err = drainRows(rows, tmpl.Capture, vars)
 					rows.Close()
 				}
 			} else {
-				if w.useTX {
-					_, err = tx.ExecContext(ctx, finalSQL, finalArgs...)
+				var res sql.Result
+				res, err = db.ExecContext(ctx, finalSQL, finalArgs...)
+				if err == nil {
+					n, _ = res.RowsAffected()
+				}
+			}
+			elapsed := time.Since(stmtStart)
+			tolerated := err != nil && plan.tolerates(i, err)
+			if ctx.Err() == nil {
+				if tolerated {
+					w.rec.RecordStatement(plan.labels[i], elapsed, n, nil)
+					w.rec.RecordTolerated(plan.labels[i], err)
 				} else {
-					_, err = conn.ExecContext(ctx, finalSQL, finalArgs...)
+					w.rec.RecordStatement(plan.labels[i], elapsed, n, err)
 				}
 			}
-			if err != nil {
+			if err != nil && !tolerated {
 				log.Printf("Worker %d: ERROR failed to execute query or iterate rows: %v", w.id, err)
-				if w.useTX {
+				if plan.useTX {
 					_ = tx.Rollback()
+					if ctx.Err() == nil {
+						w.rec.RecordRollback()
+					}
 				}
-				return
+				return err
+			}
+
+			if plan.think[i] != nil && !sleepUntil(ctx, time.Now().Add(thinkTime(plan.think[i]))) {
+				// Shutdown while thinking.
+				if plan.useTX {
+					_ = tx.Rollback()
+				}
+				return ctx.Err()
 			}
 		}
 	}
 
-	if w.useTX {
+	if plan.useTX {
 		if err := tx.Commit(); err != nil {
 			log.Printf("Worker %d: ERROR failed to commit transaction: %v", w.id, err)
+			w.recordError(ctx, err)
+			return err
 		}
+		w.rec.RecordCommit()
 	}
+	return nil
 }
 
-func handleArrayParams(sql string, args []interface{}) (string, []interface{}) {
-	finalSQL := ""
-	sqlParts := strings.Split(sql, "?")
-
-	if len(sqlParts)-1 != len(args) {
-		return sql, args
-	}
-
-	newArgs := make([]interface{}, 0, len(args))
-	for i, arg := range args {
-		finalSQL += sqlParts[i]
-		arr, ok := arg.([]interface{})
-		if ok {
-			if len(arr) == 0 {
-				// Handle empty array case, maybe return an error or a specific SQL syntax
-				// For now, we just add a single NULL placeholder to avoid syntax errors.
-				finalSQL += "?"
-				newArgs = append(newArgs, nil)
-				continue
-			}
-			placeholders := strings.Repeat("?,", len(arr))
-			placeholders = strings.TrimSuffix(placeholders, ",")
-			finalSQL += placeholders
-			newArgs = append(newArgs, arr...)
-		} else {
-			finalSQL += "?"
-			newArgs = append(newArgs, arg)
+// execer runs statements, either in a *sql.Tx or directly on a *sql.Conn.
+type execer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// endTx ends a transaction.
+type endTx interface {
+	Commit() error
+	Rollback() error
+}
+
+// begin starts the transaction of plan on conn. It returns what the
+// statements of the transaction run on and how to end it.
+func begin(ctx context.Context, conn *sql.Conn, plan *txPlan) (execer, endTx, error) {
+	if plan.startTx == "" {
+		tx, err := conn.BeginTx(ctx, plan.txOptions)
+		if err != nil {
+			return nil, nil, err
 		}
+		return tx, tx, nil
 	}
-	finalSQL += sqlParts[len(sqlParts)-1]
+	if plan.isolation != "" {
+		if _, err := conn.ExecContext(ctx, "SET TRANSACTION ISOLATION LEVEL "+plan.isolation); err != nil {
+			return nil, nil, err
+		}
+	}
+	if _, err := conn.ExecContext(ctx, plan.startTx); err != nil {
+		return nil, nil, err
+	}
+	return conn, rawTx{conn}, nil
+}
 
-	// fmt.Println(finalSQL, newArgs) // Debug print
-	return finalSQL, newArgs
+// rawTx ends a transaction started with a raw statement. It does not use
+// the session context, so that the transaction is still ended when the
+// session is cancelled.
+type rawTx struct {
+	conn *sql.Conn
+}
+
+func (t rawTx) Commit() error {
+	_, err := t.conn.ExecContext(context.Background(), "COMMIT")
+	return err
+}
+
+func (t rawTx) Rollback() error {
+	_, err := t.conn.ExecContext(context.Background(), "ROLLBACK")
+	return err
+}
+
+// recordError records an error that is not tied to a template statement,
+// unless it was caused by shutdown.
+func (w *Worker) recordError(ctx context.Context, err error) {
+	if ctx.Err() == nil {
+		w.rec.RecordError(err)
+	}
 }
@@ -0,0 +1,174 @@
+package worker
+
+import (
+	"context"
+	"database/sql/driver"
+	"database_workload/config"
+	"database_workload/stats"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// newTestWorker returns a worker running tx against fake, and the collector
+// of its measurements.
+func newTestWorker(t *testing.T, fake *fakeConnector, tx config.Transaction, retry *config.RetryPolicy) (*Worker, *stats.Collector) {
+	txs, err := newTxPicker([]config.Transaction{tx})
+	if err != nil {
+		t.Fatalf("failed to create picker: %v", err)
+	}
+	collector := stats.NewCollector()
+	rec := collector.NewRecorder()
+	return &Worker{
+		id:    1,
+		txs:   txs,
+		retry: newRetryPolicy(retry),
+		db:    openFakeDB(t, fake, rec),
+		rec:   rec,
+	}, collector
+}
+
+const transferSQL = "UPDATE accounts SET balance = balance - 1 WHERE id = ?"
+
+// transfer is a transaction updating a random account drawn once per session.
+func transfer() config.Transaction {
+	min, max := int64(1), int64(1000000000)
+	account := "account"
+	return config.Transaction{
+		Name:           "transfer",
+		Weight:         1,
+		UseTransaction: true,
+		SessionVars:    map[string]config.Param{account: {Type: "number", RandomMode: "uniform", Min: &min, Max: &max}},
+		Templates:      []config.Template{{SQL: transferSQL, Params: []config.Param{{Type: "var", Var: &account}}}},
+	}
+}
+
+func TestRunSessionRetry(t *testing.T) {
+	deadlock := &mysql.MySQLError{Number: 1213}
+	fake := &fakeConnector{errs: map[string][]error{transferSQL: {deadlock, deadlock}}}
+	w, collector := newTestWorker(t, fake, transfer(), &config.RetryPolicy{MaxAttempts: 3, Backoff: config.Duration(time.Millisecond)})
+
+	w.runSession(context.Background(), time.Time{})
+
+	want := []string{"BEGIN", transferSQL, "ROLLBACK", "BEGIN", transferSQL, "ROLLBACK", "BEGIN", transferSQL, "COMMIT"}
+	if !reflect.DeepEqual(fake.conn.executed, want) {
+		t.Fatalf("expected %v, got %v", want, fake.conn.executed)
+	}
+	// Every attempt runs with the session variables of the first one.
+	first := fake.conn.args[1]
+	for _, i := range []int{4, 7} {
+		if !reflect.DeepEqual(fake.conn.args[i], first) {
+			t.Errorf("expected args %v on every attempt, got %v", first, fake.conn.args[i])
+		}
+	}
+
+	snap := collector.Collect()
+	if snap.Sessions != 1 || snap.SessionErrors != 0 {
+		t.Errorf("expected 1 successful session, got %d sessions and %d errors", snap.Sessions, snap.SessionErrors)
+	}
+	if snap.Retries != 2 || snap.Transactions["transfer"].Retries != 2 {
+		t.Errorf("expected 2 retries, got %d (%d for transfer)", snap.Retries, snap.Transactions["transfer"].Retries)
+	}
+	if snap.Commits != 1 || snap.Rollbacks != 2 {
+		t.Errorf("expected 1 commit and 2 rollbacks, got %d and %d", snap.Commits, snap.Rollbacks)
+	}
+	if s := snap.Templates["transfer.0"]; s.Count != 3 || s.Errors != 2 {
+		t.Errorf("expected 3 statements with 2 errors, got %d and %d", s.Count, s.Errors)
+	}
+}
+
+func TestRunSessionRetry_CapturedSessionVar(t *testing.T) {
+	query, insert := "SELECT id FROM accounts LIMIT 1", "INSERT INTO audit VALUES (1)"
+	fake := &fakeConnector{
+		errs:    map[string][]error{insert: {&mysql.MySQLError{Number: 1213}}},
+		results: map[string]*fakeResult{query: {columns: []string{"id"}, rows: [][]driver.Value{{int64(0)}}}},
+	}
+	tx := transfer()
+	tx.Templates = append(tx.Templates,
+		config.Template{SQL: query, Capture: []config.Capture{{Column: "id", Var: "account"}}},
+		config.Template{SQL: insert})
+	w, _ := newTestWorker(t, fake, tx, &config.RetryPolicy{MaxAttempts: 2, Backoff: config.Duration(time.Millisecond)})
+
+	w.runSession(context.Background(), time.Time{})
+
+	want := []string{"BEGIN", transferSQL, query, insert, "ROLLBACK", "BEGIN", transferSQL, query, insert, "COMMIT"}
+	if !reflect.DeepEqual(fake.conn.executed, want) {
+		t.Fatalf("expected %v, got %v", want, fake.conn.executed)
+	}
+	// The retry runs with the generated account, not the one captured by
+	// the failed attempt.
+	if !reflect.DeepEqual(fake.conn.args[6], fake.conn.args[1]) {
+		t.Errorf("expected args %v on the retry, got %v", fake.conn.args[1], fake.conn.args[6])
+	}
+}
+
+func TestRunSessionRetry_Exhausted(t *testing.T) {
+	deadlock := &mysql.MySQLError{Number: 1213}
+	fake := &fakeConnector{errs: map[string][]error{transferSQL: {deadlock, deadlock, deadlock}}}
+	w, collector := newTestWorker(t, fake, transfer(), &config.RetryPolicy{MaxAttempts: 2, Backoff: config.Duration(time.Millisecond)})
+
+	w.runSession(context.Background(), time.Time{})
+
+	snap := collector.Collect()
+	if snap.Sessions != 1 || snap.SessionErrors != 1 {
+		t.Errorf("expected 1 failed session, got %d sessions and %d errors", snap.Sessions, snap.SessionErrors)
+	}
+	if snap.Retries != 1 {
+		t.Errorf("expected 1 retry, got %d", snap.Retries)
+	}
+	if snap.Commits != 0 || snap.Rollbacks != 2 {
+		t.Errorf("expected no commit and 2 rollbacks, got %d and %d", snap.Commits, snap.Rollbacks)
+	}
+}
+
+func TestRunSessionRetry_NotRetryable(t *testing.T) {
+	duplicate := &mysql.MySQLError{Number: 1062}
+	fake := &fakeConnector{errs: map[string][]error{transferSQL: {duplicate}}}
+	w, collector := newTestWorker(t, fake, transfer(), &config.RetryPolicy{MaxAttempts: 3, Backoff: config.Duration(time.Millisecond)})
+
+	w.runSession(context.Background(), time.Time{})
+
+	snap := collector.Collect()
+	if snap.Sessions != 1 || snap.SessionErrors != 1 || snap.Retries != 0 {
+		t.Errorf("expected 1 failed session without retries, got %d sessions, %d errors and %d retries",
+			snap.Sessions, snap.SessionErrors, snap.Retries)
+	}
+}
+
+func TestExecSessionTolerated(t *testing.T) {
+	insert, update := "INSERT INTO orders VALUES (1)", "UPDATE stock SET qty = qty - 1"
+	fake := &fakeConnector{errs: map[string][]error{insert: {&mysql.MySQLError{Number: 1062}}}}
+	w, collector := newTestWorker(t, fake, config.Transaction{
+		Name:           "order",
+		Weight:         1,
+		UseTransaction: true,
+		Templates: []config.Template{
+			{SQL: insert, ToleratedErrors: []string{"duplicate_key"}},
+			{SQL: update},
+		},
+	}, nil)
+
+	w.runSession(context.Background(), time.Time{})
+
+	// The tolerated error neither rolls back nor ends the session.
+	want := []string{"BEGIN", insert, update, "COMMIT"}
+	if !reflect.DeepEqual(fake.conn.executed, want) {
+		t.Fatalf("expected %v, got %v", want, fake.conn.executed)
+	}
+	snap := collector.Collect()
+	if snap.Sessions != 1 || snap.SessionErrors != 0 {
+		t.Errorf("expected 1 successful session, got %d sessions and %d errors", snap.Sessions, snap.SessionErrors)
+	}
+	if snap.Tolerated != 1 || snap.Commits != 1 || snap.Rollbacks != 0 {
+		t.Errorf("expected 1 tolerated error, 1 commit and no rollback, got %d, %d and %d", snap.Tolerated, snap.Commits, snap.Rollbacks)
+	}
+	if s := snap.Templates["order.0"]; s.Count != 1 || s.Errors != 0 || s.Tolerated != 1 {
+		t.Errorf("expected 1 statement counted as successful with 1 tolerated error, got %d, %d errors and %d tolerated",
+			s.Count, s.Errors, s.Tolerated)
+	}
+	if len(snap.Errors) != 0 {
+		t.Errorf("expected no errors, got %v", snap.Errors)
+	}
+}